@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -19,14 +20,39 @@ import (
 	rtmanager "sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
+// GracefulStopper stop mingle client and wait it exit
+type GracefulStopper interface {
+	// Shutdown stop mingle client and blocks until the manager, informers and
+	// in-flight handlers exit, returns ErrShutdownTimeout when exceeded timeout,
+	// otherwise returns the error that caused the stop.
+	Shutdown(timeout time.Duration) error
+
+	// Done returns a channel that's closed when mingle client exited
+	Done() <-chan struct{}
+
+	// Err returns the error that caused the stop, returns nil when stopped normally or still running
+	Err() error
+}
+
+// HealthChecker optional interface of api.MingleClient, returns the result of the last health check
+type HealthChecker interface {
+	// LastHealthCheck returns the time and error of the last health check, zero time when never checked
+	LastHealthCheck() (time.Time, error)
+}
+
 type client struct {
 	*Options
 
 	clusterCfg     api.ClusterCfgInfo
-	stopCh         chan struct{}
-	connected      bool
+	connected      int32
 	started        int32
+	l              sync.Mutex
 	internalCancel context.CancelFunc
+	done           chan struct{}
+	stopErr        error
+	inflight       int64
+	lastCheckTime  time.Time
+	lastCheckErr   error
 	informerList   []rtcache.Informer
 
 	kubeRestConfig   *rest.Config
@@ -44,7 +70,7 @@ func NewMingleClient(clusterCfg api.ClusterCfgInfo, opt *Options) (api.MingleCli
 	cli := &client{
 		Options:      opt,
 		clusterCfg:   clusterCfg,
-		stopCh:       make(chan struct{}),
+		done:         make(chan struct{}),
 		informerList: []rtcache.Informer{},
 	}
 
@@ -81,6 +107,11 @@ func (c *client) preCheck() error {
 		c.Options.ExecTimeout = defaultExecTimeout
 	}
 
+	// graceful shutdown timeout check
+	if c.Options.GracefulShutdownTimeout <= 0 {
+		c.Options.GracefulShutdownTimeout = defaultGracefulShutdownTimeout
+	}
+
 	// set QPS and Burst
 	if c.QPS > 0 && c.Burst > 0 {
 		if len(c.SetKubeRestConfigFnList) == 0 {
@@ -132,6 +163,7 @@ func (c *client) initialization() error {
 		LeaderElectionID:        c.LeaderElectionID,
 		MetricsBindAddress:      "0",
 		HealthProbeBindAddress:  "0",
+		GracefulShutdownTimeout: &c.GracefulShutdownTimeout,
 
 		// webhook configuration
 		// TODO: expose most field.
@@ -150,20 +182,26 @@ func (c *client) initialization() error {
 	return nil
 }
 
-func (c *client) autoHealthCheck() {
+func (c *client) autoHealthCheck(ctx context.Context) {
 	clusterHealthCheckOnce := func() {
 		ok, err := healthRequestWithTimeout(c.kubeInterface.Discovery().RESTClient(), c.ExecTimeout)
+		if err == nil && !ok {
+			err = errors.New("health check response is not ok")
+		}
+		c.l.Lock()
+		c.lastCheckTime, c.lastCheckErr = time.Now(), err
+		c.l.Unlock()
 		if err != nil {
 			klog.Errorf("cluster %s health check failed %+v", c.clusterCfg.GetName(), err)
 		}
 
-		if !c.connected && ok {
+		if !c.IsConnected() && ok {
 			// Health check success and not connected will print this.
 			// It will only be printed the first time and when the check is restored.
 			klog.Infof("cluster %s health check successed.", c.clusterCfg.GetName())
 		}
 
-		c.connected = ok
+		c.setConnected(ok)
 	}
 
 	// first check
@@ -171,7 +209,7 @@ func (c *client) autoHealthCheck() {
 
 	// it will pointless when interval less than 1s
 	if c.HealthCheckInterval < time.Second {
-		klog.Warningf("cluster %s not enabled health check, interval must be greater than 1s")
+		klog.Warningf("cluster %s not enabled health check, interval must be greater than 1s", c.clusterCfg.GetName())
 		return
 	}
 
@@ -182,7 +220,7 @@ func (c *client) autoHealthCheck() {
 		select {
 		case <-timer.C:
 			clusterHealthCheckOnce()
-		case <-c.stopCh:
+		case <-ctx.Done():
 			return
 		}
 	}
@@ -190,46 +228,130 @@ func (c *client) autoHealthCheck() {
 
 // Start client and blocks until the context is cancelled
 // Returns an error if there is an error starting
+// It returns after the manager, informers and in-flight handlers exited.
 func (c *client) Start(ctx context.Context) error {
-	if !atomic.CompareAndSwapInt32(&c.started, 0, 1) {
+	// set cancel before started, so that Shutdown always stop the started client
+	c.l.Lock()
+	if atomic.LoadInt32(&c.started) == 1 {
+		c.l.Unlock()
 		return fmt.Errorf("client %s can't repeat start", c.clusterCfg.GetName())
 	}
-
-	var err error
-	ctx, c.internalCancel = context.WithCancel(ctx)
-
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithCancel(ctx)
+	defer cancel()
+	c.internalCancel = cancel
+	atomic.StoreInt32(&c.started, 1)
+	c.l.Unlock()
+
+	errCh := make(chan error, 1)
 	go func() {
-		err = c.ctrlRtManager.Start(ctx)
-		if err != nil {
-			klog.Errorf("start cluster %s error: %+v", c.clusterCfg.GetName(), err)
-			close(c.stopCh)
-		}
-		klog.Warningf("cluster %s stoped.", c.clusterCfg.GetName())
+		errCh <- c.ctrlRtManager.Start(ctx)
 	}()
 
 	// health check
-	go c.autoHealthCheck()
+	go c.autoHealthCheck(ctx)
 
+	var err error
 	select {
 	case <-ctx.Done():
-		return err
-	case <-c.stopCh:
-		c.Stop()
-		return err
+		// manager exit after all runnables stopped or graceful shutdown timeout
+		err = <-errCh
+	case err = <-errCh:
+		cancel()
 	}
+	if err != nil {
+		klog.Errorf("start cluster %s error: %+v", c.clusterCfg.GetName(), err)
+	}
+
+	// drain in-flight handlers
+	if !c.waitInflight(c.GracefulShutdownTimeout) {
+		klog.Warningf("cluster %s wait in-flight handlers timeout %s", c.clusterCfg.GetName(), c.GracefulShutdownTimeout)
+	}
+	c.setConnected(false)
+
+	c.l.Lock()
+	c.stopErr = err
+	close(c.done)
+	c.l.Unlock()
+
+	klog.Warningf("cluster %s stoped.", c.clusterCfg.GetName())
+	return err
 }
 
 // Stop stop mingle client, just use with multiclient, not recommend use direct
+// It returns immediately, use Shutdown wait mingle client exit.
 func (c *client) Stop() {
+	c.l.Lock()
+	defer c.l.Unlock()
+
 	if c.internalCancel == nil {
 		return
 	}
 	c.internalCancel()
 }
 
+// Shutdown implements GracefulStopper
+func (c *client) Shutdown(timeout time.Duration) error {
+	if atomic.LoadInt32(&c.started) == 0 {
+		return nil
+	}
+	c.Stop()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-c.done:
+		return c.Err()
+	case <-timer.C:
+		return fmt.Errorf(ErrShutdownTimeout, c.clusterCfg.GetName(), timeout)
+	}
+}
+
+// Done implements GracefulStopper
+func (c *client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err implements GracefulStopper
+func (c *client) Err() error {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	return c.stopErr
+}
+
+// LastHealthCheck implements HealthChecker
+func (c *client) LastHealthCheck() (time.Time, error) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	return c.lastCheckTime, c.lastCheckErr
+}
+
+func (c *client) setConnected(connected bool) {
+	if connected {
+		atomic.StoreInt32(&c.connected, 1)
+		return
+	}
+	atomic.StoreInt32(&c.connected, 0)
+}
+
+// waitInflight wait all in-flight handlers finished, returns false when timeout
+func (c *client) waitInflight(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for atomic.LoadInt64(&c.inflight) > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(inflightCheckInterval)
+	}
+	return true
+}
+
 // IsConnected return connected status
 func (c *client) IsConnected() bool {
-	return c.connected
+	return atomic.LoadInt32(&c.connected) == 1
 }
 
 // GetClusterCfgInfo returns cluster configuration info
@@ -42,8 +42,35 @@ func (c *client) AddResourceEventHandler(obj rtclient.Object, handler cache.Reso
 	if err != nil {
 		return err
 	}
-	informer.AddEventHandler(handler)
-	return nil
+	_, err = informer.AddEventHandler(&inflightEventHandler{c: c, handler: handler})
+	return err
+}
+
+// inflightEventHandler count in-flight handlers, so Shutdown can wait them finished
+type inflightEventHandler struct {
+	c       *client
+	handler cache.ResourceEventHandler
+}
+
+func (ih *inflightEventHandler) OnAdd(obj interface{}) {
+	atomic.AddInt64(&ih.c.inflight, 1)
+	defer atomic.AddInt64(&ih.c.inflight, -1)
+
+	ih.handler.OnAdd(obj)
+}
+
+func (ih *inflightEventHandler) OnUpdate(oldObj, newObj interface{}) {
+	atomic.AddInt64(&ih.c.inflight, 1)
+	defer atomic.AddInt64(&ih.c.inflight, -1)
+
+	ih.handler.OnUpdate(oldObj, newObj)
+}
+
+func (ih *inflightEventHandler) OnDelete(obj interface{}) {
+	atomic.AddInt64(&ih.c.inflight, 1)
+	defer atomic.AddInt64(&ih.c.inflight, -1)
+
+	ih.handler.OnDelete(obj)
 }
 
 // IndexFields adds an index with the given field name on the given object type
@@ -2,12 +2,17 @@ package client
 
 import (
 	"context"
+	"errors"
 	"os"
+	"runtime"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/cache"
+	rtmanager "sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
 func TestExceptionNewMingleClient(t *testing.T) {
@@ -212,3 +217,120 @@ func TestNewMingleClient(t *testing.T) {
 		return
 	}
 }
+
+type mockManager struct {
+	rtmanager.Manager
+	startErr  error
+	exitDelay time.Duration
+}
+
+func (m *mockManager) Start(ctx context.Context) error {
+	if m.startErr != nil {
+		return m.startErr
+	}
+	<-ctx.Done()
+	time.Sleep(m.exitDelay)
+	return nil
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	buildClient := func(mgr rtmanager.Manager) *client {
+		opt := DefaultOptions()
+		opt.HealthCheckInterval = 0
+		opt.GracefulShutdownTimeout = time.Second * 1
+		return &client{
+			Options:       opt,
+			clusterCfg:    DefaultClusterCfgInfo("mock"),
+			done:          make(chan struct{}),
+			kubeInterface: fake.NewSimpleClientset(),
+			ctrlRtManager: mgr,
+		}
+	}
+
+	t.Run("not started", func(t *testing.T) {
+		cli := buildClient(&mockManager{})
+		if err := cli.Shutdown(time.Millisecond * 100); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("start failed", func(t *testing.T) {
+		startErr := errors.New("mock start failed")
+		cli := buildClient(&mockManager{startErr: startErr})
+		if err := cli.Start(context.TODO()); err != startErr {
+			t.Errorf("expect %v but got %v", startErr, err)
+		}
+		select {
+		case <-cli.Done():
+		default:
+			t.Error("client exited, Done should be closed")
+		}
+		if cli.Err() != startErr {
+			t.Errorf("expect %v but got %v", startErr, cli.Err())
+		}
+	})
+
+	t.Run("wait manager exit", func(t *testing.T) {
+		cli := buildClient(&mockManager{exitDelay: time.Millisecond * 200})
+		go cli.Start(context.TODO())
+		time.Sleep(time.Millisecond * 50)
+
+		begin := time.Now()
+		if err := cli.Shutdown(time.Second * 1); err != nil {
+			t.Error(err)
+		}
+		if time.Since(begin) < time.Millisecond*200 {
+			t.Error("Shutdown should wait manager exit")
+		}
+		if cli.IsConnected() {
+			t.Error("stopped client should be disconnected")
+		}
+	})
+
+	t.Run("shutdown just started", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			cli := buildClient(&mockManager{})
+			go cli.Start(context.TODO())
+			for atomic.LoadInt32(&cli.started) == 0 {
+				runtime.Gosched()
+			}
+			if err := cli.Shutdown(time.Second * 1); err != nil {
+				t.Fatalf("started client should be stopped, but got %+v", err)
+			}
+		}
+	})
+
+	t.Run("shutdown timeout", func(t *testing.T) {
+		cli := buildClient(&mockManager{exitDelay: time.Second * 1})
+		go cli.Start(context.TODO())
+		time.Sleep(time.Millisecond * 50)
+
+		if err := cli.Shutdown(time.Millisecond * 100); err == nil {
+			t.Error("manager exit slowly, Shutdown should timeout")
+		}
+		<-cli.Done()
+	})
+
+	t.Run("drain in-flight handlers", func(t *testing.T) {
+		cli := buildClient(&mockManager{})
+		handled := int32(0)
+		handler := &inflightEventHandler{c: cli, handler: cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				time.Sleep(time.Millisecond * 300)
+				atomic.StoreInt32(&handled, 1)
+			},
+		}}
+
+		go cli.Start(context.TODO())
+		time.Sleep(time.Millisecond * 50)
+		go handler.OnAdd(&corev1.Pod{})
+		time.Sleep(time.Millisecond * 50)
+
+		if err := cli.Shutdown(time.Second * 1); err != nil {
+			t.Error(err)
+		}
+		if atomic.LoadInt32(&handled) != 1 {
+			t.Error("Shutdown should wait in-flight handler finished")
+		}
+	})
+}
@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"time"
 
 	"github.com/symcn/api"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -48,6 +49,7 @@ type FakeClient struct {
 	WatchFunc                   func(src rtclient.Object, queue api.WorkQueue, handler api.EventHandler, predicates ...api.Predicate) error
 	GetClusterCfgInfoFunc       func() api.ClusterCfgInfo
 	IsConnectedFunc             func() bool
+	LastHealthCheckFunc         func() (time.Time, error)
 }
 
 func NewFackeClient(clusterCfg api.ClusterCfgInfo, opt *Options) (api.MingleClient, error) {
@@ -252,6 +254,14 @@ func (f *FakeClient) IsConnected() bool {
 	return f.IsConnectedFunc()
 }
 
+// LastHealthCheck implements HealthChecker
+func (f *FakeClient) LastHealthCheck() (time.Time, error) {
+	if f.LastHealthCheckFunc == nil {
+		return time.Time{}, nil
+	}
+	return f.LastHealthCheckFunc()
+}
+
 // Start implements api.MingleClient
 func (f *FakeClient) Start(ctx context.Context) error {
 	select {
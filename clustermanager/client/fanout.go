@@ -0,0 +1,267 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/configuration"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	defaultFanOutConcurrency = 10
+	defaultFanOutTimeout     = time.Second * 30
+)
+
+// ErrFanOutCanceled the cluster not answered before enough clusters succeeded
+var ErrFanOutCanceled = errors.New("fan-out canceled, enough clusters answered")
+
+// FanOutOptions options of fan-out
+type FanOutOptions struct {
+	// Clusters cluster names, use all connected clusters when empty
+	Clusters []string
+	// Concurrency max clusters invoked parallel, default 10
+	Concurrency int
+	// Timeout per-cluster timeout, default 30s
+	Timeout time.Duration
+	// MinSuccess cancel remaining clusters when succeeded clusters reached, wait all clusters when 0
+	MinSuccess int
+	// Selector skip the clusters which labels not match, nil matches all clusters
+	Selector labels.Selector
+}
+
+// FanOutResult per-cluster result of fan-out
+type FanOutResult struct {
+	// Results value with cluster name, FanOutList returns rtclient.ObjectList
+	Results map[string]interface{}
+	// Errors error with cluster name
+	Errors map[string]error
+}
+
+// FanOutFunc invoked with each cluster
+type FanOutFunc func(ctx context.Context, cli api.MingleClient) (interface{}, error)
+
+// ProxyFanOutFunc invoked with each proxy cluster
+type ProxyFanOutFunc func(ctx context.Context, cli api.MingleProxyClient) (interface{}, error)
+
+// FanOutClient fan-out query across clusters of multiclient
+type FanOutClient interface {
+	// FanOut invoke fn with clusters concurrently, returns partial results and errors
+	FanOut(ctx context.Context, opts *FanOutOptions, fn FanOutFunc) *FanOutResult
+
+	// FanOutList list objects with clusters concurrently, read from the cache of each cluster
+	FanOutList(ctx context.Context, opts *FanOutOptions, list rtclient.ObjectList, listOpts ...rtclient.ListOption) *FanOutResult
+}
+
+// ProxyFanOutClient fan-out query across clusters of proxy multiclient
+type ProxyFanOutClient interface {
+	// FanOut invoke fn with proxy clusters concurrently, returns partial results and errors
+	FanOut(ctx context.Context, opts *FanOutOptions, fn ProxyFanOutFunc) *FanOutResult
+
+	// FanOutList list objects with proxy clusters concurrently, request API server through cluster-gateway
+	FanOutList(ctx context.Context, opts *FanOutOptions, list rtclient.ObjectList, listOpts ...rtclient.ListOption) *FanOutResult
+}
+
+// Err aggregate all cluster errors, returns nil when no error
+func (r *FanOutResult) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(r.Errors))
+	for name := range r.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	errs := make([]error, 0, len(names))
+	for _, name := range names {
+		errs = append(errs, fmt.Errorf("cluster %s: %+v", name, r.Errors[name]))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// FanOut implements FanOutClient
+func (mc *multiClient) FanOut(ctx context.Context, opts *FanOutOptions, fn FanOutFunc) *FanOutResult {
+	opts = completeFanOutOptions(opts)
+
+	clients := map[string]api.MingleClient{}
+	result := newFanOutResult()
+	if len(opts.Clusters) == 0 {
+		for _, cli := range mc.GetAllConnected() {
+			clients[cli.GetClusterCfgInfo().GetName()] = cli
+		}
+	} else {
+		for _, name := range opts.Clusters {
+			cli, err := mc.GetConnectedWithName(name)
+			if err != nil {
+				result.Errors[name] = err
+				continue
+			}
+			clients[name] = cli
+		}
+	}
+
+	names := make([]string, 0, len(clients))
+	for name, cli := range clients {
+		if configuration.MatchSelector(cli.GetClusterCfgInfo(), opts.Selector) {
+			names = append(names, name)
+		}
+	}
+	fanOut(ctx, opts, names, result, func(ctx context.Context, name string) (interface{}, error) {
+		return fn(ctx, clients[name])
+	})
+	return result
+}
+
+// FanOutList implements FanOutClient
+func (mc *multiClient) FanOutList(ctx context.Context, opts *FanOutOptions, list rtclient.ObjectList, listOpts ...rtclient.ListOption) *FanOutResult {
+	return mc.FanOut(ctx, opts, func(ctx context.Context, cli api.MingleClient) (interface{}, error) {
+		obj := list.DeepCopyObject().(rtclient.ObjectList)
+		if err := cli.GetCtrlRtClient().List(ctx, obj, listOpts...); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	})
+}
+
+// FanOut implements ProxyFanOutClient
+func (pm *proxyMultiClient) FanOut(ctx context.Context, opts *FanOutOptions, fn ProxyFanOutFunc) *FanOutResult {
+	opts = completeFanOutOptions(opts)
+
+	all := map[string]api.MingleProxyClient{}
+	for _, cli := range pm.GetAll() {
+		all[cli.GetClusterCfgInfo().GetName()] = cli
+	}
+
+	clients := all
+	result := newFanOutResult()
+	if len(opts.Clusters) > 0 {
+		clients = make(map[string]api.MingleProxyClient, len(opts.Clusters))
+		for _, name := range opts.Clusters {
+			cli, ok := all[name]
+			if !ok {
+				result.Errors[name] = fmt.Errorf(ErrClientNotExist, name)
+				continue
+			}
+			clients[name] = cli
+		}
+	}
+
+	names := make([]string, 0, len(clients))
+	for name, cli := range clients {
+		if configuration.MatchSelector(cli.GetClusterCfgInfo(), opts.Selector) {
+			names = append(names, name)
+		}
+	}
+	fanOut(ctx, opts, names, result, func(ctx context.Context, name string) (interface{}, error) {
+		return fn(ctx, clients[name])
+	})
+	return result
+}
+
+// FanOutList implements ProxyFanOutClient
+func (pm *proxyMultiClient) FanOutList(ctx context.Context, opts *FanOutOptions, list rtclient.ObjectList, listOpts ...rtclient.ListOption) *FanOutResult {
+	return pm.FanOut(ctx, opts, func(ctx context.Context, cli api.MingleProxyClient) (interface{}, error) {
+		var runtimeClient rtclient.Client
+		if accessor, ok := cli.(RuntimeClientAccessor); ok {
+			var err error
+			if runtimeClient, err = accessor.GetRuntimeClientWithError(); err != nil {
+				return nil, err
+			}
+		} else if runtimeClient = cli.GetRuntimeClient(); runtimeClient == nil {
+			return nil, fmt.Errorf("proxy cluster %s runtime client is nil", cli.GetClusterCfgInfo().GetName())
+		}
+
+		obj := list.DeepCopyObject().(rtclient.ObjectList)
+		if err := runtimeClient.List(ctx, obj, listOpts...); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	})
+}
+
+func newFanOutResult() *FanOutResult {
+	return &FanOutResult{
+		Results: map[string]interface{}{},
+		Errors:  map[string]error{},
+	}
+}
+
+func completeFanOutOptions(opts *FanOutOptions) *FanOutOptions {
+	completed := FanOutOptions{}
+	if opts != nil {
+		completed = *opts
+	}
+	if completed.Concurrency <= 0 {
+		completed.Concurrency = defaultFanOutConcurrency
+	}
+	if completed.Timeout <= 0 {
+		completed.Timeout = defaultFanOutTimeout
+	}
+	return &completed
+}
+
+// fanOut invoke fn with names under bounded concurrency, records into result,
+// cancel remaining clusters when MinSuccess reached.
+func fanOut(ctx context.Context, opts *FanOutOptions, names []string, result *FanOutResult, fn func(ctx context.Context, name string) (interface{}, error)) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		l             sync.Mutex
+		succeeded     int
+		earlyCanceled bool
+	)
+	record := func(name string, value interface{}, err error) {
+		l.Lock()
+		defer l.Unlock()
+
+		if err != nil {
+			if earlyCanceled && errors.Is(err, context.Canceled) {
+				err = ErrFanOutCanceled
+			}
+			result.Errors[name] = err
+			return
+		}
+		result.Results[name] = value
+		succeeded++
+		if opts.MinSuccess > 0 && succeeded >= opts.MinSuccess && !earlyCanceled {
+			earlyCanceled = true
+			cancel()
+		}
+	}
+
+	sem := make(chan struct{}, opts.Concurrency)
+	wg := sync.WaitGroup{}
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				record(name, nil, ctx.Err())
+				return
+			}
+			if ctx.Err() != nil {
+				record(name, nil, ctx.Err())
+				return
+			}
+
+			clusterCtx, clusterCancel := context.WithTimeout(ctx, opts.Timeout)
+			defer clusterCancel()
+			value, err := fn(clusterCtx, name)
+			record(name, value, err)
+		}(name)
+	}
+	wg.Wait()
+}
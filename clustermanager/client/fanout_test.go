@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/configuration"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestFanOut(t *testing.T) {
+	buildFakeClient := func(name string, connected bool, objs ...rtclient.Object) api.MingleClient {
+		runtimeClient := fake.NewClientBuilder().WithObjects(objs...).Build()
+		return &FakeClient{
+			ClusterCfg:          configuration.NewFakeClusterCfgInfo("", api.KubeConfigTypeRawString, "", name),
+			IsConnectedFunc:     func() bool { return connected },
+			GetCtrlRtClientFunc: func() rtclient.Client { return runtimeClient },
+		}
+	}
+	buildPod := func(name string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}}
+	}
+
+	mc := &multiClient{
+		MingleClientMap: map[string]api.MingleClient{
+			"cluster-1": buildFakeClient("cluster-1", true, buildPod("a")),
+			"cluster-2": buildFakeClient("cluster-2", true, buildPod("a"), buildPod("b")),
+			"cluster-3": buildFakeClient("cluster-3", false, buildPod("a")),
+		},
+	}
+
+	t.Run("list all connected clusters", func(t *testing.T) {
+		result := mc.FanOutList(context.TODO(), nil, &corev1.PodList{})
+		if len(result.Results) != 2 || result.Err() != nil {
+			t.Fatalf("expect 2 results without error, but got %d and %+v", len(result.Results), result.Err())
+		}
+		if len(result.Results["cluster-2"].(*corev1.PodList).Items) != 2 {
+			t.Errorf("cluster-2 should list 2 pods, but got %+v", result.Results["cluster-2"])
+		}
+	})
+
+	t.Run("partial errors", func(t *testing.T) {
+		result := mc.FanOut(context.TODO(), &FanOutOptions{Clusters: []string{"cluster-1", "cluster-2", "cluster-3", "cluster-4"}}, func(ctx context.Context, cli api.MingleClient) (interface{}, error) {
+			if cli.GetClusterCfgInfo().GetName() == "cluster-2" {
+				return nil, errors.New("forbidden")
+			}
+			return cli.GetClusterCfgInfo().GetName(), nil
+		})
+		if len(result.Results) != 1 || result.Results["cluster-1"] != "cluster-1" {
+			t.Errorf("expect cluster-1 result, but got %+v", result.Results)
+		}
+		if len(result.Errors) != 3 {
+			t.Errorf("expect 3 errors, but got %+v", result.Errors)
+		}
+		if result.Err() == nil {
+			t.Error("aggregate error should not be nil")
+		}
+	})
+
+	t.Run("per-cluster timeout", func(t *testing.T) {
+		result := mc.FanOut(context.TODO(), &FanOutOptions{Timeout: time.Millisecond * 50}, func(ctx context.Context, cli api.MingleClient) (interface{}, error) {
+			if cli.GetClusterCfgInfo().GetName() == "cluster-1" {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			return nil, nil
+		})
+		if !errors.Is(result.Errors["cluster-1"], context.DeadlineExceeded) || len(result.Results) != 1 {
+			t.Errorf("cluster-1 should timeout, but got %+v and %+v", result.Results, result.Errors)
+		}
+	})
+
+	t.Run("bounded concurrency and early cancel", func(t *testing.T) {
+		names := make([]string, 0, 20)
+		for i := 0; i < 20; i++ {
+			names = append(names, fmt.Sprintf("cluster-%d", i))
+		}
+
+		var running, maxRunning int32
+		result := newFanOutResult()
+		fanOut(context.TODO(), completeFanOutOptions(&FanOutOptions{Concurrency: 3, MinSuccess: 5}), names, result, func(ctx context.Context, name string) (interface{}, error) {
+			cur := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if cur <= max || atomic.CompareAndSwapInt32(&maxRunning, max, cur) {
+					break
+				}
+			}
+
+			select {
+			case <-time.After(time.Millisecond * 20):
+				return name, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		})
+
+		if atomic.LoadInt32(&maxRunning) > 3 {
+			t.Errorf("concurrency should be bounded by 3, but got %d", maxRunning)
+		}
+		if len(result.Results) < 5 || len(result.Results)+len(result.Errors) != 20 {
+			t.Errorf("expect at least 5 results and 20 in total, but got %d and %d", len(result.Results), len(result.Errors))
+		}
+		for name, err := range result.Errors {
+			if !errors.Is(err, ErrFanOutCanceled) {
+				t.Errorf("cluster %s should be canceled, but got %+v", name, err)
+			}
+		}
+	})
+}
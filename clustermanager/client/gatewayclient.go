@@ -0,0 +1,47 @@
+package client
+
+import (
+	"net/http"
+
+	multicluster "github.com/oam-dev/cluster-gateway/pkg/apis/cluster/transport"
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/configuration"
+	"k8s.io/client-go/rest"
+)
+
+// NewGatewayMingleClient build api.MingleClient through cluster-gateway proxy path,
+// the kubeconfig of clusterCfg is the hub cluster which cluster-gateway installed,
+// the name of clusterCfg is the managed cluster registered in cluster-gateway.
+// controller-runtime manager, informers and clients all request with the proxy path,
+// so it supports Watch and handler same as NewMingleClient.
+func NewGatewayMingleClient(clusterCfg api.ClusterCfgInfo, opt *Options) (api.MingleClient, error) {
+	if opt == nil {
+		return NewMingleClient(clusterCfg, opt)
+	}
+
+	// don't modify the shared options
+	gatewayOpt := *opt
+	gatewayOpt.SetKubeRestConfigFnList = make([]api.SetKubeRestConfig, 0, len(opt.SetKubeRestConfigFnList)+1)
+	gatewayOpt.SetKubeRestConfigFnList = append(gatewayOpt.SetKubeRestConfigFnList, opt.SetKubeRestConfigFnList...)
+	if clusterCfg != nil {
+		gatewayOpt.SetKubeRestConfigFnList = append(gatewayOpt.SetKubeRestConfigFnList, wrapClusterGatewayProxy(configuration.GetClusterOriginalName(clusterCfg)))
+	}
+
+	return NewMingleClient(clusterCfg, &gatewayOpt)
+}
+
+// BuildGatewayClient BuildClientFunc with cluster-gateway, use it as MultiClientConfig.BuildClientFunc
+// with the ClusterConfigurationManager of cluster-gateway.
+func BuildGatewayClient(clsInfo api.ClusterCfgInfo, opts *Options) (api.MingleClient, error) {
+	return NewGatewayMingleClient(clsInfo, opts)
+}
+
+// wrapClusterGatewayProxy prepend cluster-gateway proxy path to every request,
+// build new round tripper each time, because the round tripper holds delegate.
+func wrapClusterGatewayProxy(clusterName string) api.SetKubeRestConfig {
+	return func(config *rest.Config) {
+		config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+			return multicluster.NewProxyPathPrependingClusterGatewayRoundTripper(clusterName).NewRoundTripper(rt)
+		})
+	}
+}
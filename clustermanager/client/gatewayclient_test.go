@@ -0,0 +1,96 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/configuration"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestGatewayMingleClient(t *testing.T) {
+	clusterName := "member-1"
+	proxyPrefix := fmt.Sprintf("/apis/cluster.core.oam.dev/v1alpha1/clustergateways/%s/proxy", clusterName)
+
+	var l sync.Mutex
+	unexpected := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, proxyPrefix) {
+			l.Lock()
+			unexpected = append(unexpected, r.URL.Path)
+			l.Unlock()
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch strings.TrimPrefix(r.URL.Path, proxyPrefix) {
+		case "/healthz":
+			w.Write([]byte("ok"))
+		case "/api":
+			w.Write([]byte(`{"kind":"APIVersions","versions":["v1"]}`))
+		case "/apis":
+			w.Write([]byte(`{"kind":"APIGroupList","apiVersion":"v1","groups":[]}`))
+		case "/api/v1":
+			w.Write([]byte(`{"kind":"APIResourceList","groupVersion":"v1","resources":[]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	kubeconfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: %s
+  name: hub
+contexts:
+- context:
+    cluster: hub
+    user: hub
+  name: hub
+current-context: hub
+users:
+- name: hub
+  user:
+    token: fake
+`, server.URL)
+
+	opt := DefaultOptions()
+	opt.HealthCheckInterval = 0
+	cli, err := NewGatewayMingleClient(configuration.NewFakeClusterCfgInfo(kubeconfig, api.KubeConfigTypeRawString, "", clusterName), opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opt.SetKubeRestConfigFnList) != 0 {
+		t.Errorf("shared options should not be modified, but got %d SetKubeRestConfig", len(opt.SetKubeRestConfigFnList))
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go cli.Start(ctx)
+
+	err = wait.PollImmediate(time.Millisecond*10, time.Second*5, func() (bool, error) {
+		return cli.IsConnected(), nil
+	})
+	if err != nil {
+		t.Error("gateway mingle client should be connected through proxy path")
+	}
+	if _, err = cli.GetKubeInterface().Discovery().ServerGroups(); err != nil {
+		t.Errorf("discovery through proxy path failed %+v", err)
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	if len(unexpected) > 0 {
+		t.Errorf("all requests should prepend proxy path, but got %v", unexpected)
+	}
+}
@@ -0,0 +1,190 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/symcn/api"
+	"k8s.io/klog/v2"
+)
+
+var (
+	defaultHealthzEndpoint = "/healthz"
+	defaultReadyzEndpoint  = "/readyz"
+)
+
+// ReadinessPolicyType readiness policy type
+type ReadinessPolicyType string
+
+// ReadinessAllSynced all clusters connected and synced, pending clusters are counted as not synced
+// ReadinessMinSynced at least MinSynced clusters connected and synced
+// ReadinessCriticalClusters all CriticalClusters connected and synced
+const (
+	ReadinessAllSynced        ReadinessPolicyType = "AllSynced"
+	ReadinessMinSynced        ReadinessPolicyType = "MinSynced"
+	ReadinessCriticalClusters ReadinessPolicyType = "CriticalClusters"
+)
+
+// ReadinessPolicy decide when the multiclient is ready
+type ReadinessPolicy struct {
+	Type ReadinessPolicyType
+	// MinSynced used with ReadinessMinSynced
+	MinSynced int
+	// CriticalClusters used with ReadinessCriticalClusters
+	CriticalClusters []string
+}
+
+// ClusterHealth health and sync state of one cluster
+type ClusterHealth struct {
+	Name      string `json:"name"`
+	Connected bool   `json:"connected"`
+	Synced    bool   `json:"synced"`
+	Pending   bool   `json:"pending,omitempty"`
+}
+
+// HealthStatus health response of multiclient
+type HealthStatus struct {
+	Status    string          `json:"status"`
+	Reason    string          `json:"reason,omitempty"`
+	Total     int             `json:"total"`
+	Connected int             `json:"connected"`
+	Synced    int             `json:"synced"`
+	Pending   int             `json:"pending"`
+	Clusters  []ClusterHealth `json:"clusters"`
+}
+
+// HealthHandler healthz and readyz http handler with multiclient
+type HealthHandler struct {
+	mc     api.MultiMingleClient
+	policy ReadinessPolicy
+}
+
+// NewHealthHandler build HealthHandler, policy is empty use ReadinessAllSynced
+func NewHealthHandler(mc api.MultiMingleClient, policy *ReadinessPolicy) *HealthHandler {
+	hh := &HealthHandler{
+		mc:     mc,
+		policy: ReadinessPolicy{Type: ReadinessAllSynced},
+	}
+	if policy != nil && policy.Type != "" {
+		hh.policy = *policy
+	}
+	return hh
+}
+
+// RegisterHTTPHandler register /healthz and /readyz
+func (hh *HealthHandler) RegisterHTTPHandler(f func(pattern string, handler http.Handler)) {
+	f(defaultHealthzEndpoint, hh.Healthz())
+	f(defaultReadyzEndpoint, hh.Readyz())
+}
+
+// Healthz returns liveness handler, it always ok while multiclient is running
+func (hh *HealthHandler) Healthz() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := hh.clusterStatus()
+		ok := true
+		if mc, isMulti := hh.mc.(*multiClient); isMulti && !mc.isRunning() {
+			ok = false
+			status.Reason = "multiclient is not running"
+		}
+		writeHealthStatus(w, status, ok)
+	})
+}
+
+// Readyz returns readiness handler with ReadinessPolicy
+func (hh *HealthHandler) Readyz() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, ok := hh.CheckReady()
+		writeHealthStatus(w, status, ok)
+	})
+}
+
+// CheckReady returns health status and whether ready with ReadinessPolicy
+func (hh *HealthHandler) CheckReady() (HealthStatus, bool) {
+	status := hh.clusterStatus()
+
+	switch hh.policy.Type {
+	case ReadinessAllSynced:
+		if status.Synced != status.Total {
+			status.Reason = fmt.Sprintf("%d of %d clusters synced", status.Synced, status.Total)
+			return status, false
+		}
+	case ReadinessMinSynced:
+		if status.Synced < hh.policy.MinSynced {
+			status.Reason = fmt.Sprintf("%d clusters synced, at least %d", status.Synced, hh.policy.MinSynced)
+			return status, false
+		}
+	case ReadinessCriticalClusters:
+		synced := make(map[string]bool, len(status.Clusters))
+		for _, cls := range status.Clusters {
+			synced[cls.Name] = cls.Connected && cls.Synced
+		}
+		for _, name := range hh.policy.CriticalClusters {
+			if !synced[name] {
+				status.Reason = fmt.Sprintf("critical cluster %s not connected or synced", name)
+				return status, false
+			}
+		}
+	default:
+		status.Reason = fmt.Sprintf("not support readiness policy %s", hh.policy.Type)
+		return status, false
+	}
+	return status, true
+}
+
+func (hh *HealthHandler) clusterStatus() HealthStatus {
+	clis := hh.mc.GetAll()
+	status := HealthStatus{
+		Total:    len(clis),
+		Clusters: make([]ClusterHealth, 0, len(clis)),
+	}
+
+	exist := make(map[string]bool, len(clis))
+	for _, cli := range clis {
+		health := ClusterHealth{
+			Name:      cli.GetClusterCfgInfo().GetName(),
+			Connected: cli.IsConnected(),
+		}
+		exist[health.Name] = true
+		health.Synced = health.Connected && cli.HasSynced()
+
+		if health.Connected {
+			status.Connected++
+		}
+		if health.Synced {
+			status.Synced++
+		}
+		status.Clusters = append(status.Clusters, health)
+	}
+
+	// build or start failed clusters are configured but not connected
+	if ps, ok := hh.mc.(PendingClusterStatus); ok {
+		for _, pending := range ps.GetPendingClusters() {
+			if exist[pending.Name] {
+				continue
+			}
+			status.Total++
+			status.Pending++
+			status.Clusters = append(status.Clusters, ClusterHealth{Name: pending.Name, Pending: true})
+		}
+	}
+	sort.Slice(status.Clusters, func(i, j int) bool { return status.Clusters[i].Name < status.Clusters[j].Name })
+	return status
+}
+
+func writeHealthStatus(w http.ResponseWriter, status HealthStatus, ok bool) {
+	code := http.StatusOK
+	status.Status = "ok"
+	if !ok {
+		code = http.StatusServiceUnavailable
+		status.Status = "failed"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		klog.ErrorS(err, "write health status failed")
+	}
+}
@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/configuration"
+)
+
+func TestHealthHandler(t *testing.T) {
+	buildFakeClient := func(name string, connected, synced bool) api.MingleClient {
+		return &FakeClient{
+			ClusterCfg:      configuration.NewFakeClusterCfgInfo("", api.KubeConfigTypeRawString, "", name),
+			IsConnectedFunc: func() bool { return connected },
+			HasSyncedFunc:   func() bool { return synced },
+		}
+	}
+
+	mc := &multiClient{
+		MingleClientMap: map[string]api.MingleClient{
+			"cluster-1": buildFakeClient("cluster-1", true, true),
+			"cluster-2": buildFakeClient("cluster-2", true, false),
+			"cluster-3": buildFakeClient("cluster-3", false, true),
+		},
+		stopCh: make(chan struct{}),
+	}
+
+	request := func(handler http.Handler) (int, HealthStatus) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		status := HealthStatus{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Code, status
+	}
+
+	t.Run("healthz not running", func(t *testing.T) {
+		code, _ := request(NewHealthHandler(mc, nil).Healthz())
+		if code != http.StatusServiceUnavailable {
+			t.Errorf("multiclient not started, expect %d but got %d", http.StatusServiceUnavailable, code)
+		}
+	})
+
+	mc.started = 1
+	t.Run("healthz running", func(t *testing.T) {
+		code, status := request(NewHealthHandler(mc, nil).Healthz())
+		if code != http.StatusOK {
+			t.Errorf("expect %d but got %d", http.StatusOK, code)
+		}
+		if status.Total != 3 || status.Connected != 2 || status.Synced != 1 {
+			t.Errorf("expect total 3 connected 2 synced 1, but got %+v", status)
+		}
+		if len(status.Clusters) != 3 || status.Clusters[0].Name != "cluster-1" {
+			t.Errorf("clusters should sorted by name, but got %+v", status.Clusters)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		policy *ReadinessPolicy
+		code   int
+	}{
+		{"all synced", nil, http.StatusServiceUnavailable},
+		{"min synced satisfied", &ReadinessPolicy{Type: ReadinessMinSynced, MinSynced: 1}, http.StatusOK},
+		{"min synced unsatisfied", &ReadinessPolicy{Type: ReadinessMinSynced, MinSynced: 2}, http.StatusServiceUnavailable},
+		{"critical synced", &ReadinessPolicy{Type: ReadinessCriticalClusters, CriticalClusters: []string{"cluster-1"}}, http.StatusOK},
+		{"critical not synced", &ReadinessPolicy{Type: ReadinessCriticalClusters, CriticalClusters: []string{"cluster-1", "cluster-3"}}, http.StatusServiceUnavailable},
+		{"critical not exist", &ReadinessPolicy{Type: ReadinessCriticalClusters, CriticalClusters: []string{"cluster-4"}}, http.StatusServiceUnavailable},
+		{"unsupport policy", &ReadinessPolicy{Type: "unknown"}, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run("readyz "+tt.name, func(t *testing.T) {
+			code, status := request(NewHealthHandler(mc, tt.policy).Readyz())
+			if code != tt.code {
+				t.Errorf("expect %d but got %d, reason: %s", tt.code, code, status.Reason)
+			}
+		})
+	}
+
+	t.Run("register http handler", func(t *testing.T) {
+		patterns := map[string]bool{}
+		NewHealthHandler(mc, nil).RegisterHTTPHandler(func(pattern string, handler http.Handler) {
+			patterns[pattern] = true
+		})
+		if !patterns["/healthz"] || !patterns["/readyz"] {
+			t.Errorf("expect register /healthz and /readyz, but got %v", patterns)
+		}
+	})
+
+	t.Run("readyz pending cluster", func(t *testing.T) {
+		pmc := &multiClient{
+			MingleClientMap: map[string]api.MingleClient{
+				"cluster-1": buildFakeClient("cluster-1", true, true),
+			},
+			pendingClusterMap: map[string]*PendingCluster{
+				"cluster-2": {Name: "cluster-2", Reason: ReasonBuildFailed},
+			},
+		}
+		status, ok := NewHealthHandler(pmc, nil).CheckReady()
+		if ok {
+			t.Error("pending cluster not synced, should not be ready")
+		}
+		if status.Total != 2 || status.Synced != 1 || status.Pending != 1 {
+			t.Errorf("expect total 2 synced 1 pending 1, but got %+v", status)
+		}
+		if len(status.Clusters) != 2 || status.Clusters[1].Name != "cluster-2" || !status.Clusters[1].Pending {
+			t.Errorf("expect cluster-2 pending, but got %+v", status.Clusters)
+		}
+
+		if _, ok = NewHealthHandler(pmc, &ReadinessPolicy{Type: ReadinessCriticalClusters, CriticalClusters: []string{"cluster-2"}}).CheckReady(); ok {
+			t.Error("critical cluster pending, should not be ready")
+		}
+	})
+}
+
+func TestHealthHandlerWithMultiClient(t *testing.T) {
+	cfgManager := &configuration.FakeConfiguration{
+		GetAllFunc: func() ([]api.ClusterCfgInfo, error) {
+			return []api.ClusterCfgInfo{
+				configuration.NewFakeClusterCfgInfo("1", api.KubeConfigTypeRawString, "", "cluster-1"),
+			}, nil
+		},
+	}
+	mcc := NewMultiClientConfig()
+	mcc.ClusterCfgManager = cfgManager
+	mcc.BuildClientFunc = NewFackeClient
+	cc, err := Complete(mcc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc, err := cc.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	go mc.Start(ctx)
+	time.Sleep(time.Millisecond * 100)
+
+	hh := NewHealthHandler(mc, nil)
+	if _, ok := hh.CheckReady(); !ok {
+		t.Error("all clusters synced, should be ready")
+	}
+
+	cancel()
+	time.Sleep(time.Millisecond * 100)
+	rec := httptest.NewRecorder()
+	hh.Healthz().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("multiclient stopped, expect %d but got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+}
@@ -0,0 +1,187 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/symcn/api"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// ClusterObject object with the cluster name it belongs to
+type ClusterObject struct {
+	ClusterName string
+	Object      rtclient.Object
+}
+
+// MultiClusterIndexer query informer caches across all connected clusters, without any API calls.
+// Only the types registered with AddResourceEventHandler, Watch, TriggerSync or SetIndexField can be queried,
+// the clusters which the type not registered with, such as excluded by selector, are skipped.
+type MultiClusterIndexer interface {
+	// ListByField list objects which indexed field equals value,
+	// the field must registered with SetIndexField.
+	ListByField(ctx context.Context, list rtclient.ObjectList, field, value string, opts ...rtclient.ListOption) ([]ClusterObject, error)
+
+	// ListByLabels list objects which labels match selector
+	ListByLabels(ctx context.Context, list rtclient.ObjectList, selector labels.Selector, opts ...rtclient.ListOption) ([]ClusterObject, error)
+}
+
+// ListByField implements MultiClusterIndexer
+func (mc *multiClient) ListByField(ctx context.Context, list rtclient.ObjectList, field, value string, opts ...rtclient.ListOption) ([]ClusterObject, error) {
+	gvk, err := mc.objectListGVK(list)
+	if err != nil {
+		return nil, err
+	}
+
+	mc.l.Lock()
+	fields, registered := mc.registeredTypes[gvk]
+	registered = registered && fields.Has(field)
+	mc.l.Unlock()
+	if !registered {
+		return nil, fmt.Errorf("index field %s of %s not registered, register with SetIndexField first", field, gvk.String())
+	}
+
+	return mc.listFromCache(ctx, gvk, list, append(opts, rtclient.MatchingFields{field: value})...)
+}
+
+// ListByLabels implements MultiClusterIndexer
+func (mc *multiClient) ListByLabels(ctx context.Context, list rtclient.ObjectList, selector labels.Selector, opts ...rtclient.ListOption) ([]ClusterObject, error) {
+	gvk, err := mc.objectListGVK(list)
+	if err != nil {
+		return nil, err
+	}
+
+	mc.l.Lock()
+	_, registered := mc.registeredTypes[gvk]
+	mc.l.Unlock()
+	if !registered {
+		return nil, fmt.Errorf("%s not registered, informer cache not exist", gvk.String())
+	}
+
+	return mc.listFromCache(ctx, gvk, list, append(opts, rtclient.MatchingLabelsSelector{Selector: selector})...)
+}
+
+// listFromCache list with cache of connected clusters which registered the type, returns partial results with aggregate error
+func (mc *multiClient) listFromCache(ctx context.Context, gvk schema.GroupVersionKind, list rtclient.ObjectList, opts ...rtclient.ListOption) ([]ClusterObject, error) {
+	result := []ClusterObject{}
+	var errs []error
+	for _, cli := range mc.GetAllConnected() {
+		if !mc.clusterHasType(cli, gvk) {
+			// list would start informer lazily and request API
+			continue
+		}
+		clusterName := cli.GetClusterCfgInfo().GetName()
+		obj := list.DeepCopyObject().(rtclient.ObjectList)
+		if err := cli.GetCtrlRtCache().List(ctx, obj, opts...); err != nil {
+			errs = append(errs, fmt.Errorf("cluster %s list from cache failed %+v", clusterName, err))
+			continue
+		}
+
+		items, err := meta.ExtractList(obj)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cluster %s extract list failed %+v", clusterName, err))
+			continue
+		}
+		for _, item := range items {
+			if o, ok := item.(rtclient.Object); ok {
+				result = append(result, ClusterObject{ClusterName: clusterName, Object: o})
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ClusterName != result[j].ClusterName {
+			return result[i].ClusterName < result[j].ClusterName
+		}
+		if result[i].Object.GetNamespace() != result[j].Object.GetNamespace() {
+			return result[i].Object.GetNamespace() < result[j].Object.GetNamespace()
+		}
+		return result[i].Object.GetName() < result[j].Object.GetName()
+	})
+	return result, utilerrors.NewAggregate(errs)
+}
+
+// registerType record the type has informer cache, and the index field if not empty, must hold lock
+func (mc *multiClient) registerType(obj rtclient.Object, field string) {
+	gvk, err := apiutil.GVKForObject(obj, mc.scheme())
+	if err != nil {
+		return
+	}
+
+	if mc.registeredTypes == nil {
+		mc.registeredTypes = map[schema.GroupVersionKind]sets.String{}
+	}
+	fields, ok := mc.registeredTypes[gvk]
+	if !ok {
+		fields = sets.NewString()
+		mc.registeredTypes[gvk] = fields
+	}
+	if field != "" {
+		fields.Insert(field)
+	}
+}
+
+// markClusterType record the type has informer cache in the cluster
+func (mc *multiClient) markClusterType(cli api.MingleClient, obj rtclient.Object) {
+	gvk, err := apiutil.GVKForObject(obj, mc.scheme())
+	if err != nil {
+		return
+	}
+
+	mc.typeL.Lock()
+	defer mc.typeL.Unlock()
+
+	if mc.clusterTypes == nil {
+		mc.clusterTypes = map[api.MingleClient]map[schema.GroupVersionKind]struct{}{}
+	}
+	types, ok := mc.clusterTypes[cli]
+	if !ok {
+		types = map[schema.GroupVersionKind]struct{}{}
+		mc.clusterTypes[cli] = types
+	}
+	types[gvk] = struct{}{}
+}
+
+func (mc *multiClient) clusterHasType(cli api.MingleClient, gvk schema.GroupVersionKind) bool {
+	mc.typeL.Lock()
+	defer mc.typeL.Unlock()
+
+	_, ok := mc.clusterTypes[cli][gvk]
+	return ok
+}
+
+// forgetClusterTypes remove the types of stopped cluster
+func (mc *multiClient) forgetClusterTypes(cli api.MingleClient) {
+	mc.typeL.Lock()
+	defer mc.typeL.Unlock()
+
+	delete(mc.clusterTypes, cli)
+}
+
+// objectListGVK returns the item GroupVersionKind of list
+func (mc *multiClient) objectListGVK(list rtclient.ObjectList) (schema.GroupVersionKind, error) {
+	gvk, err := apiutil.GVKForObject(list, mc.scheme())
+	if err != nil {
+		return gvk, err
+	}
+	gvk.Kind = strings.TrimSuffix(gvk.Kind, "List")
+	return gvk, nil
+}
+
+func (mc *multiClient) scheme() *runtime.Scheme {
+	if mc.CompletedConfig != nil && mc.completeConfig != nil && mc.MultiClientConfig != nil &&
+		mc.Options != nil && mc.Scheme != nil {
+		return mc.Scheme
+	}
+	return clientgoscheme.Scheme
+}
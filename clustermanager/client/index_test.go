@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/configuration"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	rtcache "sigs.k8s.io/controller-runtime/pkg/cache"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// fakeIndexCache list pods with label selector and spec.nodeName field selector
+type fakeIndexCache struct {
+	rtcache.Cache
+	pods  []corev1.Pod
+	lists int32
+}
+
+func (c *fakeIndexCache) List(ctx context.Context, list rtclient.ObjectList, opts ...rtclient.ListOption) error {
+	atomic.AddInt32(&c.lists, 1)
+	listOpts := &rtclient.ListOptions{}
+	listOpts.ApplyOptions(opts)
+
+	podList := list.(*corev1.PodList)
+	for _, pod := range c.pods {
+		if listOpts.LabelSelector != nil && !listOpts.LabelSelector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		if listOpts.FieldSelector != nil && !listOpts.FieldSelector.Matches(fields.Set{"spec.nodeName": pod.Spec.NodeName}) {
+			continue
+		}
+		podList.Items = append(podList.Items, pod)
+	}
+	return nil
+}
+
+func TestMultiClusterIndexer(t *testing.T) {
+	buildPod := func(name, node, app string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Labels: map[string]string{"app": app}},
+			Spec:       corev1.PodSpec{NodeName: node},
+		}
+	}
+	buildFakeClient := func(name string, pods ...corev1.Pod) api.MingleClient {
+		cache := &fakeIndexCache{pods: pods}
+		return &FakeClient{
+			ClusterCfg:         configuration.NewFakeClusterCfgInfo("", api.KubeConfigTypeRawString, "", name),
+			GetCtrlRtCacheFunc: func() rtcache.Cache { return cache },
+		}
+	}
+
+	mc := &multiClient{
+		MingleClientMap: map[string]api.MingleClient{
+			"cluster-2": buildFakeClient("cluster-2", buildPod("b", "node-1", "foo"), buildPod("c", "node-2", "bar")),
+			"cluster-1": buildFakeClient("cluster-1", buildPod("a", "node-1", "foo")),
+		},
+	}
+
+	if _, err := mc.ListByLabels(context.TODO(), &corev1.PodList{}, labels.Everything()); err == nil {
+		t.Error("unregistered type must be error")
+	}
+
+	if err := mc.TriggerSync(&corev1.Pod{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mc.ListByField(context.TODO(), &corev1.PodList{}, "spec.nodeName", "node-1"); err == nil {
+		t.Error("unregistered index field must be error")
+	}
+
+	result, err := mc.ListByLabels(context.TODO(), &corev1.PodList{}, labels.SelectorFromSet(labels.Set{"app": "foo"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 2 || result[0].ClusterName != "cluster-1" || result[1].ClusterName != "cluster-2" || result[1].Object.GetName() != "b" {
+		t.Errorf("expect pod a in cluster-1 and pod b in cluster-2, but got %+v", result)
+	}
+
+	if err = mc.SetIndexField(&corev1.Pod{}, "spec.nodeName", func(o rtclient.Object) []string { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	result, err = mc.ListByField(context.TODO(), &corev1.PodList{}, "spec.nodeName", "node-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 1 || result[0].ClusterName != "cluster-2" || result[0].Object.GetName() != "c" {
+		t.Errorf("expect pod c in cluster-2, but got %+v", result)
+	}
+}
+
+func TestMultiClusterIndexerWithSelector(t *testing.T) {
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "a"}}
+	prodCache := &fakeIndexCache{pods: []corev1.Pod{pod}}
+	testCache := &fakeIndexCache{pods: []corev1.Pod{pod}}
+
+	mc := &multiClient{
+		MingleClientMap: map[string]api.MingleClient{
+			"prod": &FakeClient{
+				ClusterCfg:         configuration.BuildClusterCfgInfoWithMetadata("prod", api.KubeConfigTypeRawString, "", "", map[string]string{"env": "prod"}, nil),
+				GetCtrlRtCacheFunc: func() rtcache.Cache { return prodCache },
+			},
+			"test": &FakeClient{
+				ClusterCfg:         configuration.BuildClusterCfgInfoWithMetadata("test", api.KubeConfigTypeRawString, "", "", map[string]string{"env": "test"}, nil),
+				GetCtrlRtCacheFunc: func() rtcache.Cache { return testCache },
+			},
+		},
+	}
+
+	err := mc.AddResourceEventHandlerWithSelector(labels.SelectorFromSet(labels.Set{"env": "prod"}), &corev1.Pod{}, cache.ResourceEventHandlerFuncs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := mc.ListByLabels(context.TODO(), &corev1.PodList{}, labels.Everything())
+	if err != nil {
+		t.Fatalf("cluster excluded by selector should not be error, but got %+v", err)
+	}
+	if len(result) != 1 || result[0].ClusterName != "prod" {
+		t.Errorf("expect pod in prod cluster only, but got %+v", result)
+	}
+	if atomic.LoadInt32(&testCache.lists) != 0 {
+		t.Error("cache of excluded cluster should not be listed")
+	}
+}
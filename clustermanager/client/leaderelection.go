@@ -0,0 +1,182 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+)
+
+var (
+	defaultLeaderElectionNamespace = "default"
+	defaultLeaderElectionID        = "symcn-multi-client"
+	defaultLeaseDuration           = time.Second * 15
+	defaultRenewDeadline           = time.Second * 10
+	defaultRetryPeriod             = time.Second * 2
+)
+
+// LeaderElectionConfig leader election for the whole multiclient.
+// Only one Lease is used, it lives in the manager cluster, so every replica
+// competes for the same lock no matter how many member clusters are managed.
+type LeaderElectionConfig struct {
+	// Enabled enable multiclient leader election
+	Enabled bool
+
+	// Namespace is the namespace of the Lease in the manager cluster
+	Namespace string
+	// ID is the name of the Lease
+	ID string
+	// Identity is the holder identity of this replica, default is hostname_uuid
+	Identity string
+
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+
+	// OnStartedLeading is invoked when this replica becomes leader,
+	// ctx will be cancelled when the leadership is lost.
+	OnStartedLeading func(ctx context.Context)
+	// OnStoppedLeading is invoked when this replica loses the leadership,
+	// all member clusters already stopped.
+	OnStoppedLeading func()
+}
+
+// LeaderElectionStatus reports the leader election status of multiclient
+type LeaderElectionStatus interface {
+	// IsLeader returns true if this replica holds the leadership,
+	// always true when leader election not enabled
+	IsLeader() bool
+}
+
+// NewLeaderElectionConfig build LeaderElectionConfig with default value
+func NewLeaderElectionConfig() *LeaderElectionConfig {
+	return &LeaderElectionConfig{
+		Enabled:       true,
+		Namespace:     defaultLeaderElectionNamespace,
+		ID:            defaultLeaderElectionID,
+		LeaseDuration: defaultLeaseDuration,
+		RenewDeadline: defaultRenewDeadline,
+		RetryPeriod:   defaultRetryPeriod,
+	}
+}
+
+func (lec *LeaderElectionConfig) complete() error {
+	if lec.Namespace == "" {
+		lec.Namespace = defaultLeaderElectionNamespace
+	}
+	if lec.ID == "" {
+		return errors.New("leader election id is empty")
+	}
+	if lec.Identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("get hostname for leader election identity failed %+v", err)
+		}
+		lec.Identity = hostname + "_" + string(uuid.NewUUID())
+	}
+	if lec.LeaseDuration <= 0 {
+		lec.LeaseDuration = defaultLeaseDuration
+	}
+	if lec.RenewDeadline <= 0 {
+		lec.RenewDeadline = defaultRenewDeadline
+	}
+	if lec.RetryPeriod <= 0 {
+		lec.RetryPeriod = defaultRetryPeriod
+	}
+	return nil
+}
+
+func (mc *multiClient) leaderElectionEnabled() bool {
+	return mc.MultiClusterLeaderElection != nil && mc.MultiClusterLeaderElection.Enabled
+}
+
+// IsLeader implements LeaderElectionStatus
+func (mc *multiClient) IsLeader() bool {
+	if !mc.leaderElectionEnabled() {
+		return true
+	}
+	return atomic.LoadInt32(&mc.leading) == 1
+}
+
+// runWithLeaderElection blocks until the context is cancelled,
+// when leadership is lost, stop all member clusters and enter election again.
+func (mc *multiClient) runWithLeaderElection(ctx context.Context) error {
+	if mc.ManagerKubeInterface == nil {
+		return errors.New("leader election manager cluster kubernetes interface is nil")
+	}
+	lec := mc.MultiClusterLeaderElection
+
+	lock, err := buildLeaseLock(mc.ManagerKubeInterface, lec.Namespace, lec.ID, lec.Identity)
+	if err != nil {
+		return err
+	}
+
+	for {
+		// runDone is closed when the members of current term already stopped
+		runDone := make(chan struct{})
+		le, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+			Lock:            lock,
+			Name:            lec.ID,
+			LeaseDuration:   lec.LeaseDuration,
+			RenewDeadline:   lec.RenewDeadline,
+			RetryPeriod:     lec.RetryPeriod,
+			ReleaseOnCancel: true,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(leaderCtx context.Context) {
+					defer close(runDone)
+
+					klog.InfoS("Became leader, start multiclient.", "identity", lec.Identity, "lease", lec.Namespace+"/"+lec.ID)
+					atomic.StoreInt32(&mc.leading, 1)
+					if lec.OnStartedLeading != nil {
+						go lec.OnStartedLeading(leaderCtx)
+					}
+
+					if err := mc.run(leaderCtx); err != nil {
+						klog.ErrorS(err, "multiclient run failed with leadership")
+					}
+				},
+				OnStoppedLeading: func() {
+					if atomic.CompareAndSwapInt32(&mc.leading, 1, 0) {
+						// wait all member clusters stopped
+						<-runDone
+						klog.InfoS("Leadership lost, all member clusters stopped.", "identity", lec.Identity)
+						if lec.OnStoppedLeading != nil {
+							lec.OnStoppedLeading()
+						}
+					}
+				},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("build leader elector failed %+v", err)
+		}
+
+		le.Run(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+			klog.InfoS("Re-enter multiclient leader election.", "identity", lec.Identity)
+		}
+	}
+}
+
+func buildLeaseLock(kubeInterface kubernetes.Interface, namespace, id, identity string) (resourcelock.Interface, error) {
+	return resourcelock.New(
+		resourcelock.LeasesResourceLock,
+		namespace,
+		id,
+		kubeInterface.CoreV1(),
+		kubeInterface.CoordinationV1(),
+		resourcelock.ResourceLockConfig{Identity: identity},
+	)
+}
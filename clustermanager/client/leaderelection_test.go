@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/configuration"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMultiClientLeaderElection(t *testing.T) {
+	kubeInterface := fake.NewSimpleClientset()
+	cfgManager := &configuration.FakeConfiguration{
+		GetAllFunc: func() ([]api.ClusterCfgInfo, error) {
+			return []api.ClusterCfgInfo{
+				configuration.NewFakeClusterCfgInfo("1", api.KubeConfigTypeRawString, "", "cluster-1"),
+				configuration.NewFakeClusterCfgInfo("2", api.KubeConfigTypeRawString, "", "cluster-2"),
+			}, nil
+		},
+	}
+
+	var started, stopped int32
+	buildMultiClient := func(identity string) api.MultiMingleClient {
+		lec := NewLeaderElectionConfig()
+		lec.ID = "mock-leader-election"
+		lec.Identity = identity
+		lec.LeaseDuration = time.Second * 2
+		lec.RenewDeadline = time.Second * 1
+		lec.RetryPeriod = time.Millisecond * 200
+		lec.OnStartedLeading = func(ctx context.Context) {
+			atomic.AddInt32(&started, 1)
+		}
+		lec.OnStoppedLeading = func() {
+			atomic.AddInt32(&stopped, 1)
+		}
+
+		mcc := NewMultiClientConfig()
+		mcc.FetchInterval = 0
+		mcc.ClusterCfgManager = cfgManager
+		mcc.BuildClientFunc = NewFackeClient
+		mcc.ManagerKubeInterface = kubeInterface
+		mcc.MultiClusterLeaderElection = lec
+		cc, err := Complete(mcc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mc, err := cc.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return mc
+	}
+
+	mc1 := buildMultiClient("replica-1")
+	mc2 := buildMultiClient("replica-2")
+
+	ctx1, cancel1 := context.WithCancel(context.TODO())
+	defer cancel1()
+	ctx2, cancel2 := context.WithCancel(context.TODO())
+	defer cancel2()
+
+	go mc1.Start(ctx1)
+	time.Sleep(time.Millisecond * 500)
+	go mc2.Start(ctx2)
+	time.Sleep(time.Millisecond * 500)
+
+	if !mc1.(LeaderElectionStatus).IsLeader() || mc2.(LeaderElectionStatus).IsLeader() {
+		t.Fatal("replica-1 should be the only leader")
+	}
+	if len(mc1.GetAll()) != 2 {
+		t.Errorf("leader should manage %d clusters, but got %d", 2, len(mc1.GetAll()))
+	}
+	if len(mc2.GetAll()) != 0 {
+		t.Errorf("follower should not manage any cluster, but got %d", len(mc2.GetAll()))
+	}
+	if mc2.HasSynced() {
+		t.Error("follower should not synced")
+	}
+
+	// leader exit and release the lease
+	cancel1()
+	time.Sleep(time.Second * 2)
+
+	if !mc2.(LeaderElectionStatus).IsLeader() {
+		t.Fatal("replica-2 should take over the leadership")
+	}
+	if len(mc1.GetAll()) != 0 {
+		t.Errorf("old leader should stop all clusters, but got %d", len(mc1.GetAll()))
+	}
+	if len(mc2.GetAll()) != 2 {
+		t.Errorf("new leader should manage %d clusters, but got %d", 2, len(mc2.GetAll()))
+	}
+	if atomic.LoadInt32(&started) != 2 || atomic.LoadInt32(&stopped) != 1 {
+		t.Errorf("expect started 2 and stopped 1, but got %d and %d", started, stopped)
+	}
+}
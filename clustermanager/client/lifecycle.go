@@ -0,0 +1,236 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/symcn/api"
+	"k8s.io/klog/v2"
+)
+
+var defaultClusterStateCheckInterval = time.Second * 1
+
+// ClusterEventType lifecycle event type of cluster
+type ClusterEventType string
+
+// ClusterConnected health check restored
+// ClusterDisconnected health check failed
+// ClusterConfigUpdated kubeconfig or labels modified, the client rebuilt without OnDelete and OnAdd
+// ClusterSynced all informers of the connected cluster synced
+const (
+	ClusterConnected     ClusterEventType = "Connected"
+	ClusterDisconnected  ClusterEventType = "Disconnected"
+	ClusterConfigUpdated ClusterEventType = "ConfigUpdated"
+	ClusterSynced        ClusterEventType = "Synced"
+)
+
+// ClusterEvent lifecycle event of cluster
+type ClusterEvent struct {
+	Type ClusterEventType
+	// Client the current client of cluster
+	Client api.MingleClient
+	// OldClient the replaced client, just set with ClusterConfigUpdated
+	OldClient api.MingleClient
+}
+
+// OldClusterCfgInfo returns the cluster info before ClusterConfigUpdated, nil with other events
+func (e ClusterEvent) OldClusterCfgInfo() api.ClusterCfgInfo {
+	if e.OldClient == nil {
+		return nil
+	}
+	return e.OldClient.GetClusterCfgInfo()
+}
+
+// ClusterLifecycleHandler optional interface of api.ClusterEventHandler which added with AddClusterEventHandler,
+// events of one cluster are delivered in order, different clusters are delivered parallel.
+// OnAdd and OnDelete of the handler are delivered in the same order, so no event arrives before OnAdd or after OnDelete.
+// When cluster configuration modified, the handler receives ClusterConfigUpdated instead of OnDelete and OnAdd.
+type ClusterLifecycleHandler interface {
+	OnClusterEvent(ctx context.Context, event ClusterEvent)
+}
+
+// clusterAdded and clusterDeleted deliver OnAdd and OnDelete of lifecycle handlers, never passed to OnClusterEvent
+const (
+	clusterAdded   ClusterEventType = "added"
+	clusterDeleted ClusterEventType = "deleted"
+)
+
+// clusterState the last observed state of cluster client
+type clusterState struct {
+	cli       api.MingleClient
+	connected bool
+	synced    bool
+}
+
+// queuedClusterEvent event with the handlers and context when it happened
+type queuedClusterEvent struct {
+	event       ClusterEvent
+	ctx         context.Context
+	handlerList []api.ClusterEventHandler
+	done        chan struct{}
+}
+
+// clusterEventQueue deliver events of one cluster in order
+type clusterEventQueue struct {
+	events  []*queuedClusterEvent
+	running bool
+}
+
+func isLifecycleHandler(handler api.ClusterEventHandler) bool {
+	_, ok := handler.(ClusterLifecycleHandler)
+	return ok
+}
+
+// enqueueClusterEvent deliver event to the lifecycle handlers of handlerList asynchronously,
+// keep the order of the same cluster, the returned channel closed after delivered. must hold lock
+func (mc *multiClient) enqueueClusterEvent(event ClusterEvent, handlerList []api.ClusterEventHandler) <-chan struct{} {
+	name := event.Client.GetClusterCfgInfo().GetName()
+	ctx := mc.ctx
+	if ctx == nil {
+		ctx = context.TODO()
+	}
+	qe := &queuedClusterEvent{
+		event:       event,
+		ctx:         ctx,
+		handlerList: make([]api.ClusterEventHandler, 0, len(handlerList)),
+		done:        make(chan struct{}),
+	}
+	for _, handler := range handlerList {
+		if isLifecycleHandler(handler) {
+			qe.handlerList = append(qe.handlerList, handler)
+		}
+	}
+	if len(qe.handlerList) == 0 {
+		close(qe.done)
+		return qe.done
+	}
+
+	mc.eventL.Lock()
+	defer mc.eventL.Unlock()
+
+	if mc.clusterEventQueues == nil {
+		mc.clusterEventQueues = map[string]*clusterEventQueue{}
+	}
+	q, ok := mc.clusterEventQueues[name]
+	if !ok {
+		q = &clusterEventQueue{}
+		mc.clusterEventQueues[name] = q
+	}
+	q.events = append(q.events, qe)
+	if !q.running {
+		q.running = true
+		go mc.deliverClusterEvents(name, q)
+	}
+	return qe.done
+}
+
+// deliverClusterEvents invoke handlers until the queue is empty
+func (mc *multiClient) deliverClusterEvents(name string, q *clusterEventQueue) {
+	for {
+		mc.eventL.Lock()
+		if len(q.events) == 0 {
+			q.running = false
+			delete(mc.clusterEventQueues, name)
+			mc.eventL.Unlock()
+			return
+		}
+		qe := q.events[0]
+		q.events = q.events[1:]
+		mc.eventL.Unlock()
+
+		klog.V(4).InfoS("Deliver cluster event", "clusterName", name, "type", qe.event.Type)
+		for _, handler := range qe.handlerList {
+			switch qe.event.Type {
+			case clusterAdded:
+				handler.OnAdd(qe.ctx, qe.event.Client)
+			case clusterDeleted:
+				handler.OnDelete(qe.ctx, qe.event.Client)
+			default:
+				handler.(ClusterLifecycleHandler).OnClusterEvent(qe.ctx, qe.event)
+			}
+		}
+		close(qe.done)
+	}
+}
+
+// loopCheckClusterStates check connected and synced state of clusters until the context is cancelled
+func (mc *multiClient) loopCheckClusterStates(ctx context.Context, interval time.Duration) {
+	timer := time.NewTicker(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			mc.checkClusterStatesOnce()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkClusterStatesOnce emit Connected, Disconnected and Synced when state changed
+func (mc *multiClient) checkClusterStatesOnce() {
+	mc.l.Lock()
+	clients := make(map[string]api.MingleClient, len(mc.MingleClientMap))
+	for name, cli := range mc.MingleClientMap {
+		clients[name] = cli
+	}
+	mc.l.Unlock()
+
+	events := mc.observeClusterStates(clients)
+	if len(events) == 0 {
+		return
+	}
+
+	mc.l.Lock()
+	defer mc.l.Unlock()
+
+	for _, event := range events {
+		// removed or replaced while checking, OnDelete or ClusterConfigUpdated enqueued already
+		if mc.MingleClientMap[event.Client.GetClusterCfgInfo().GetName()] != event.Client {
+			continue
+		}
+		mc.enqueueClusterEvent(event, mc.clusterEventHandlerList)
+	}
+}
+
+// observeClusterStates returns the events of state changed clusters
+func (mc *multiClient) observeClusterStates(clients map[string]api.MingleClient) []ClusterEvent {
+	mc.stateL.Lock()
+	defer mc.stateL.Unlock()
+
+	if mc.clusterStates == nil {
+		mc.clusterStates = map[string]*clusterState{}
+	}
+	for name := range mc.clusterStates {
+		if _, ok := clients[name]; !ok {
+			delete(mc.clusterStates, name)
+		}
+	}
+
+	var events []ClusterEvent
+	for name, cli := range clients {
+		state, ok := mc.clusterStates[name]
+		if !ok || state.cli != cli {
+			// new or rebuilt client, observe from beginning
+			state = &clusterState{cli: cli}
+			mc.clusterStates[name] = state
+		}
+
+		connected := cli.IsConnected()
+		if connected != state.connected {
+			state.connected = connected
+			if connected {
+				events = append(events, ClusterEvent{Type: ClusterConnected, Client: cli})
+			} else {
+				state.synced = false
+				events = append(events, ClusterEvent{Type: ClusterDisconnected, Client: cli})
+			}
+		}
+		if connected && !state.synced && cli.HasSynced() {
+			state.synced = true
+			events = append(events, ClusterEvent{Type: ClusterSynced, Client: cli})
+		}
+	}
+	return events
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/configuration"
+)
+
+// recordLifecycleHandler record all events in order
+type recordLifecycleHandler struct {
+	l       sync.Mutex
+	events  []string
+	added   int32
+	deleted int32
+}
+
+func (r *recordLifecycleHandler) OnAdd(ctx context.Context, cli api.MingleClient) {
+	atomic.AddInt32(&r.added, 1)
+	r.record("Add")
+}
+
+func (r *recordLifecycleHandler) OnDelete(ctx context.Context, cli api.MingleClient) {
+	atomic.AddInt32(&r.deleted, 1)
+	r.record("Delete")
+}
+
+func (r *recordLifecycleHandler) OnClusterEvent(ctx context.Context, event ClusterEvent) {
+	record := string(event.Type)
+	if event.Type == ClusterConfigUpdated {
+		record += ":" + event.OldClusterCfgInfo().GetKubeConfig() + "->" + event.Client.GetClusterCfgInfo().GetKubeConfig()
+	}
+	r.record(record)
+}
+
+func (r *recordLifecycleHandler) record(event string) {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	r.events = append(r.events, event)
+}
+
+func (r *recordLifecycleHandler) getEvents() []string {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	return append([]string{}, r.events...)
+}
+
+func TestClusterLifecycleEvents(t *testing.T) {
+	var (
+		kubeconfig atomic.Value
+		connected  int32 = 1
+		removed    int32
+	)
+	kubeconfig.Store("v1")
+
+	mcc := NewMultiClientConfig()
+	mcc.FetchInterval = 0
+	mcc.ClusterCfgManager = &configuration.FakeConfiguration{
+		GetAllFunc: func() ([]api.ClusterCfgInfo, error) {
+			if atomic.LoadInt32(&removed) == 1 {
+				return nil, nil
+			}
+			return []api.ClusterCfgInfo{
+				configuration.NewFakeClusterCfgInfo(kubeconfig.Load().(string), api.KubeConfigTypeRawString, "", "cluster-1"),
+			}, nil
+		},
+	}
+	mcc.BuildClientFunc = func(info api.ClusterCfgInfo, opts *Options) (api.MingleClient, error) {
+		cli, _ := NewFackeClient(info, opts)
+		cli.(*FakeClient).IsConnectedFunc = func() bool { return atomic.LoadInt32(&connected) == 1 }
+		return cli, nil
+	}
+	cc, err := Complete(mcc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli, err := cc.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc := cli.(*multiClient)
+
+	handler := &recordLifecycleHandler{}
+	mc.AddClusterEventHandler(handler)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	stopped := make(chan struct{})
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+	go func() {
+		defer close(stopped)
+		mc.Start(ctx)
+	}()
+	time.Sleep(time.Millisecond * 50)
+
+	mc.checkClusterStatesOnce()
+	atomic.StoreInt32(&connected, 0)
+	mc.checkClusterStatesOnce()
+	atomic.StoreInt32(&connected, 1)
+	mc.checkClusterStatesOnce()
+
+	kubeconfig.Store("v2")
+	if err = mc.FetchClientInfoOnce(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 50)
+
+	expected := []string{"Add", "Connected", "Synced", "Disconnected", "Connected", "Synced", "ConfigUpdated:v1->v2"}
+	events := handler.getEvents()
+	if len(events) != len(expected) {
+		t.Fatalf("expect events %v, but got %v", expected, events)
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Fatalf("expect events %v, but got %v", expected, events)
+		}
+	}
+	if atomic.LoadInt32(&handler.added) != 1 || atomic.LoadInt32(&handler.deleted) != 0 {
+		t.Errorf("configuration modified should not invoke OnAdd and OnDelete, but got added %d deleted %d", handler.added, handler.deleted)
+	}
+
+	// removed cluster receives OnDelete after all the events, and no event after OnDelete
+	mc.checkClusterStatesOnce()
+	atomic.StoreInt32(&connected, 0)
+	mc.checkClusterStatesOnce()
+	atomic.StoreInt32(&removed, 1)
+	if err = mc.FetchClientInfoOnce(); err != nil {
+		t.Fatal(err)
+	}
+	mc.checkClusterStatesOnce()
+	time.Sleep(time.Millisecond * 50)
+
+	expected = append(expected, "Connected", "Synced", "Disconnected", "Delete")
+	events = handler.getEvents()
+	if len(events) != len(expected) {
+		t.Fatalf("expect events %v, but got %v", expected, events)
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Fatalf("expect events %v, but got %v", expected, events)
+		}
+	}
+}
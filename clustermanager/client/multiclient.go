@@ -9,6 +9,10 @@ import (
 	"time"
 
 	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/configuration"
+	"github.com/symcn/pkg/clustermanager/sharding"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog/v2"
 )
 
@@ -22,8 +26,20 @@ type multiClient struct {
 	ctx                     context.Context
 	stopCh                  chan struct{}
 	started                 int32
+	leading                 int32
+	membership              *sharding.Membership
 	buildClientFunc         BuildClientFunc
 	clusterEventHandlerList []api.ClusterEventHandler
+	pendingClusterMap       map[string]*PendingCluster
+	registeredTypes         map[schema.GroupVersionKind]sets.String
+	registrationList        []*multiRegistration
+	eventL                  sync.Mutex
+	clusterEventQueues      map[string]*clusterEventQueue
+	stateL                  sync.Mutex
+	clusterStates           map[string]*clusterState
+	validationResults       map[string]configuration.ValidationResult
+	typeL                   sync.Mutex
+	clusterTypes            map[api.MingleClient]map[schema.GroupVersionKind]struct{}
 }
 
 func (mc *multiClient) Start(ctx context.Context) error {
@@ -31,30 +47,57 @@ func (mc *multiClient) Start(ctx context.Context) error {
 		return errors.New("multiclient can't repeat start")
 	}
 
+	defer mc.clean()
+
+	if mc.leaderElectionEnabled() {
+		return mc.runWithLeaderElection(ctx)
+	}
+	if mc.shardingEnabled() {
+		return mc.runWithSharding(ctx)
+	}
+	return mc.run(ctx)
+}
+
+// run fetch and start all clusters, blocks until the context is cancelled,
+// all clusters will be stopped before return.
+func (mc *multiClient) run(ctx context.Context) error {
 	// save ctx, when add new client
+	mc.l.Lock()
 	mc.ctx = ctx
+	mc.l.Unlock()
 
-	if err := mc.loopFetchClient(); err != nil {
+	if err := mc.loopFetchClient(ctx); err != nil {
 		return err
 	}
+	go mc.loopRetryPending(ctx, defaultRetryCheckInterval)
+	go mc.loopCheckClusterStates(ctx, defaultClusterStateCheckInterval)
+	if reporter, ok := mc.ClusterCfgManager.(configuration.ClusterStatusReporter); ok && mc.ClusterStatusReportInterval > 0 {
+		go mc.loopReportClusterStatus(ctx, reporter, mc.ClusterStatusReportInterval)
+	}
 
 	<-ctx.Done()
-	mc.clean()
+	mc.stopAll()
 	return nil
 }
 
-func (mc *multiClient) loopFetchClient() error {
+func (mc *multiClient) loopFetchClient(ctx context.Context) error {
 	err := mc.FetchClientInfoOnce()
 	if err != nil {
 		return err
 	}
 
+	if notifier, ok := mc.ClusterCfgManager.(configuration.ClusterCfgNotifier); ok {
+		go mc.loopNotifyClusterCfg(ctx, notifier)
+	}
+
+	// polling as resync when notifier enabled
 	if mc.FetchInterval <= 0 {
 		return nil
 	}
 	go func() {
 		var err error
 		timer := time.NewTicker(mc.FetchInterval)
+		defer timer.Stop()
 		for {
 			select {
 			case <-timer.C:
@@ -62,17 +105,77 @@ func (mc *multiClient) loopFetchClient() error {
 				if err != nil {
 					klog.ErrorS(err, "FetchClientInfoOnce failed")
 				}
+			case <-ctx.Done():
+				return
 			case <-mc.stopCh:
+				return
 			}
 		}
 	}()
 	return nil
 }
 
+// loopNotifyClusterCfg rebuild clusters when the configuration changed until the context is cancelled
+func (mc *multiClient) loopNotifyClusterCfg(ctx context.Context, notifier configuration.ClusterCfgNotifier) {
+	ch, err := notifier.Notify(ctx)
+	if err != nil {
+		klog.ErrorS(err, "Watch cluster configuration failed, fallback to polling")
+		return
+	}
+
+	for {
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			klog.V(4).InfoS("Cluster configuration changed", "type", event.Type, "name", event.Name)
+			// merge the queued events into one rebuild
+			for len(ch) > 0 {
+				<-ch
+			}
+			if err := mc.FetchClientInfoOnce(); err != nil {
+				klog.ErrorS(err, "FetchClientInfoOnce failed")
+			}
+		case <-mc.stopCh:
+			return
+		}
+	}
+}
+
+// stopAll stop all clusters, reset clusters map and wait all of them exited
+func (mc *multiClient) stopAll() {
+	mc.l.Lock()
+	stopList := make([]api.MingleClient, 0, len(mc.MingleClientMap))
+	for _, cli := range mc.MingleClientMap {
+		stopList = append(stopList, cli)
+	}
+	mc.MingleClientMap = map[string]api.MingleClient{}
+	for name := range mc.pendingClusterMap {
+		mc.resolvePending(name)
+	}
+	mc.l.Unlock()
+
+	mc.stopClusters(stopList)
+}
+
 func (mc *multiClient) clean() {
 	close(mc.stopCh)
 }
 
+// isRunning returns true if multiclient started and not stopped
+func (mc *multiClient) isRunning() bool {
+	if atomic.LoadInt32(&mc.started) == 0 {
+		return false
+	}
+	select {
+	case <-mc.stopCh:
+		return false
+	default:
+		return true
+	}
+}
+
 // AddClusterEventHandler implements api.MultiMingleClient
 func (mc *multiClient) AddClusterEventHandler(handler api.ClusterEventHandler) {
 	mc.l.Lock()
@@ -80,6 +183,10 @@ func (mc *multiClient) AddClusterEventHandler(handler api.ClusterEventHandler) {
 
 	// ignore multi client start already, and AddClusterEventHandler invoke get empty client.
 	for _, cli := range mc.MingleClientMap {
+		if isLifecycleHandler(handler) {
+			mc.enqueueClusterEvent(ClusterEvent{Type: clusterAdded, Client: cli}, []api.ClusterEventHandler{handler})
+			continue
+		}
 		handler.OnAdd(mc.ctx, cli)
 	}
 	mc.clusterEventHandlerList = append(mc.clusterEventHandlerList, handler)
@@ -91,41 +198,72 @@ func (mc *multiClient) FetchClientInfoOnce() error {
 		klog.Warningln("MultiClient not started, rebuild failed.")
 		return nil
 	}
+	if !mc.IsLeader() {
+		klog.V(4).Infoln("MultiClient is not leader, skip rebuild.")
+		return nil
+	}
 
+	stopList, err := mc.rebuild()
+	// stop without lock, modified and removed clusters wait exited
+	mc.stopClusters(stopList)
+	return err
+}
+
+// rebuild clusterClientMap, returns the clusters should be stopped
+func (mc *multiClient) rebuild() ([]api.MingleClient, error) {
 	mc.l.Lock()
 	defer mc.l.Unlock()
 
+	if mc.ctx == nil || mc.ctx.Err() != nil {
+		// context cancelled, such as leadership lost
+		return nil, nil
+	}
+
 	freshList, err := mc.ClusterCfgManager.GetAll()
 	if err != nil {
-		return fmt.Errorf("get all cluster info failed %+v", err)
+		// still give up the clusters not owned, such as sharding lease expired
+		return mc.removeNotShardOwned(), fmt.Errorf("get all cluster info failed %+v", err)
 	}
+	// just keep the clusters belong to this replica
+	freshList = mc.filterShardOwned(freshList)
 
 	freshCliMap := make(map[string]api.MingleClient, len(freshList))
+	freshNames := make(map[string]struct{}, len(freshList))
+	stopList := []api.MingleClient{}
 	var change int
 	// add and check new cluster
 	for _, freshClsInfo := range freshList {
+		freshNames[freshClsInfo.GetName()] = struct{}{}
 		// get old cluster info
 		currentCli, exist := mc.MingleClientMap[freshClsInfo.GetName()]
-		if exist &&
-			currentCli.GetClusterCfgInfo().GetKubeConfigType() == freshClsInfo.GetKubeConfigType() &&
-			currentCli.GetClusterCfgInfo().GetKubeConfig() == freshClsInfo.GetKubeConfig() &&
-			currentCli.GetClusterCfgInfo().GetKubeContext() == freshClsInfo.GetKubeContext() {
-			// kubetype, kubeconfig, kubecontext not modify
+		if exist && sameClusterCfgInfo(currentCli.GetClusterCfgInfo(), freshClsInfo) {
+			// kubetype, kubeconfig, kubecontext and labels not modify
 			freshCliMap[currentCli.GetClusterCfgInfo().GetName()] = currentCli
 			continue
 		}
 
-		cli, err := mc.buildNewCluster(freshClsInfo, mc.Options)
+		if mc.pendingNotDue(freshClsInfo) {
+			// failed before, wait backoff
+			continue
+		}
+
+		var oldCli api.MingleClient
+		if exist {
+			oldCli = currentCli
+		}
+		cli, err := mc.buildNewCluster(freshClsInfo, mc.Options, oldCli)
 		if err != nil {
 			// !import ignore err, because one cluster disconnected not affect connected cluster.
-			klog.ErrorS(err, "buildNewCluster failed (ignore!!!).", "clusterName", freshClsInfo.GetName())
+			mc.recordPending(freshClsInfo, err)
 			continue
 		}
+		mc.resolvePending(freshClsInfo.GetName())
 
 		if exist {
 			// kubeconfig modify, should stop old client
 			klog.InfoS("Configuration modified, stop old mingle client", "clusterName", cli.GetClusterCfgInfo().GetName())
-			mc.stopCluster(currentCli)
+			stopList = append(stopList, currentCli)
+			mc.enqueueClusterEvent(ClusterEvent{Type: ClusterConfigUpdated, Client: cli, OldClient: currentCli}, mc.clusterEventHandlerList)
 		}
 
 		freshCliMap[freshClsInfo.GetName()] = cli
@@ -138,10 +276,19 @@ func (mc *multiClient) FetchClientInfoOnce() error {
 		if _, ok := freshCliMap[name]; !ok {
 			change++
 			// not exist, should stop
-			go func(cli api.MingleClient) {
-				klog.InfoS("Stop mingle client", "clusterName", cli.GetClusterCfgInfo().GetName())
-				mc.stopCluster(cli)
-			}(currentCli)
+			stopList = append(stopList, currentCli)
+		}
+	}
+
+	// remove pending cluster which not exist
+	for name := range mc.pendingClusterMap {
+		if _, ok := freshNames[name]; !ok {
+			mc.resolvePending(name)
+		}
+	}
+	for name := range mc.validationResults {
+		if _, ok := freshNames[name]; !ok {
+			delete(mc.validationResults, name)
 		}
 	}
 
@@ -149,29 +296,51 @@ func (mc *multiClient) FetchClientInfoOnce() error {
 	if change > 0 {
 		mc.MingleClientMap = freshCliMap
 	}
-	return nil
+	return stopList, nil
 }
 
-func (mc *multiClient) buildNewCluster(newClsInfo api.ClusterCfgInfo, options *Options) (api.MingleClient, error) {
+// buildNewCluster build and start client, oldCli is the client replaced by it, nil when cluster added, must hold lock
+func (mc *multiClient) buildNewCluster(newClsInfo api.ClusterCfgInfo, options *Options, oldCli api.MingleClient) (api.MingleClient, error) {
+	if err := mc.validateCluster(newClsInfo); err != nil {
+		return nil, &clusterBuildError{reason: ReasonValidationFailed, err: err}
+	}
+
+	cli, err := mc.buildAndStart(mc.ctx, newClsInfo, options, mc.beforeStartHandlers())
+	if err != nil {
+		return nil, err
+	}
+
+	if oldCli == nil {
+		mc.enqueueClusterEvent(ClusterEvent{Type: clusterAdded, Client: cli}, mc.clusterEventHandlerList)
+	}
+	mc.notifyClusterAdded(mc.ctx, mc.clusterEventHandlerList, cli)
+	return cli, nil
+}
+
+// buildAndStart build client and invoke BeforeStartHandles, without lock
+func (mc *multiClient) buildAndStart(ctx context.Context, newClsInfo api.ClusterCfgInfo, options *Options, beforeStartList []api.BeforeStartHandle) (api.MingleClient, error) {
 	// build new client
 	cli, err := mc.buildClientFunc(newClsInfo, options)
 	if err != nil {
-		return nil, err
+		return nil, &clusterBuildError{reason: ReasonBuildFailed, err: err}
 	}
 
 	// start new client
-	err = start(mc.ctx, cli, mc.BeforStartHandleList)
+	err = start(ctx, cli, beforeStartList)
 	if err != nil {
-		return nil, err
+		return nil, &clusterBuildError{reason: ReasonStartFailed, err: err}
 	}
+	return cli, nil
+}
 
-	if len(mc.clusterEventHandlerList) > 0 {
-		for _, handler := range mc.clusterEventHandlerList {
-			handler.OnAdd(mc.ctx, cli)
+// notifyClusterAdded invoke OnAdd of handlers, lifecycle handlers receive OnAdd by the cluster event queue
+func (mc *multiClient) notifyClusterAdded(ctx context.Context, handlerList []api.ClusterEventHandler, cli api.MingleClient) {
+	for _, handler := range handlerList {
+		if isLifecycleHandler(handler) {
+			continue
 		}
+		handler.OnAdd(ctx, cli)
 	}
-
-	return cli, nil
 }
 
 func start(ctx context.Context, cli api.MingleClient, beforStartHandleList []api.BeforeStartHandle) error {
@@ -184,7 +353,7 @@ func start(ctx context.Context, cli api.MingleClient, beforStartHandleList []api
 	}
 
 	go func() {
-		err = cli.Start(ctx)
+		err := cli.Start(ctx)
 		if err != nil {
 			klog.ErrorS(err, "start mingle client failed", "clusterName", cli.GetClusterCfgInfo().GetName())
 		}
@@ -193,13 +362,71 @@ func start(ctx context.Context, cli api.MingleClient, beforStartHandleList []api
 	return nil
 }
 
-func (mc *multiClient) stopCluster(cli api.MingleClient) {
-	if len(mc.clusterEventHandlerList) > 0 {
-		for _, handler := range mc.clusterEventHandlerList {
-			handler.OnDelete(mc.ctx, cli)
+// stopClusters stop clusters parallel and wait all of them exited
+func (mc *multiClient) stopClusters(stopList []api.MingleClient) {
+	if len(stopList) == 0 {
+		return
+	}
+
+	mc.l.Lock()
+	ctx := mc.ctx
+	handlerList := make([]api.ClusterEventHandler, len(mc.clusterEventHandlerList))
+	copy(handlerList, mc.clusterEventHandlerList)
+	// lifecycle handlers receive OnDelete after the queued events, except the client replaced by
+	// configuration modified which the cluster still exist and ClusterConfigUpdated enqueued instead
+	deliveredList := make([]<-chan struct{}, len(stopList))
+	for i, cli := range stopList {
+		if current, ok := mc.MingleClientMap[cli.GetClusterCfgInfo().GetName()]; ok && current != cli {
+			continue
 		}
+		deliveredList[i] = mc.enqueueClusterEvent(ClusterEvent{Type: clusterDeleted, Client: cli}, handlerList)
+	}
+	mc.l.Unlock()
+
+	wg := sync.WaitGroup{}
+	for i, cli := range stopList {
+		wg.Add(1)
+		go func(cli api.MingleClient, delivered <-chan struct{}) {
+			defer wg.Done()
+			mc.stopCluster(ctx, handlerList, cli, delivered)
+		}(cli, deliveredList[i])
+	}
+	wg.Wait()
+}
+
+// stopCluster invoke OnDelete of handlers, wait lifecycle handlers delivered and stop the client, delivered nil means no need wait
+func (mc *multiClient) stopCluster(ctx context.Context, handlerList []api.ClusterEventHandler, cli api.MingleClient, delivered <-chan struct{}) {
+	klog.InfoS("Stop mingle client", "clusterName", cli.GetClusterCfgInfo().GetName())
+	mc.forgetClusterTypes(cli)
+	for _, handler := range handlerList {
+		if !isLifecycleHandler(handler) {
+			handler.OnDelete(ctx, cli)
+		}
+	}
+	if delivered != nil {
+		<-delivered
+	}
+	mc.shutdownCluster(cli)
+}
+
+// shutdownCluster stop client and wait exited with GracefulStopper
+func (mc *multiClient) shutdownCluster(cli api.MingleClient) {
+	gs, ok := cli.(GracefulStopper)
+	if !ok {
+		cli.Stop()
+		return
+	}
+	if err := gs.Shutdown(mc.gracefulShutdownTimeout()); err != nil {
+		klog.ErrorS(err, "shutdown mingle client failed", "clusterName", cli.GetClusterCfgInfo().GetName())
+	}
+}
+
+func (mc *multiClient) gracefulShutdownTimeout() time.Duration {
+	if mc.Options == nil || mc.GracefulShutdownTimeout <= 0 {
+		return defaultGracefulShutdownTimeout
 	}
-	cli.Stop()
+	// more time than manager graceful shutdown, wait in-flight handlers
+	return mc.GracefulShutdownTimeout * 2
 }
 
 func BuildNormalClient(clsInfo api.ClusterCfgInfo, opts *Options) (api.MingleClient, error) {
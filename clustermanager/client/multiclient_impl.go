@@ -4,58 +4,79 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"sync/atomic"
 
 	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/configuration"
 	"github.com/symcn/pkg/clustermanager/handler"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-// AddResourceEventHandler loop each mingleclient invoke AddResourceEventHandler
+// AddResourceEventHandler loop each mingleclient invoke AddResourceEventHandler,
+// the running clusters invoked immediately and the new clusters invoked before start
 func (mc *multiClient) AddResourceEventHandler(obj rtclient.Object, handler cache.ResourceEventHandler) error {
+	return mc.AddResourceEventHandlerWithSelector(nil, obj, handler)
+}
+
+// AddResourceEventHandlerWithSelector implements SelectorClient
+func (mc *multiClient) AddResourceEventHandlerWithSelector(selector labels.Selector, obj rtclient.Object, handler cache.ResourceEventHandler) error {
 	mc.l.Lock()
-	defer mc.l.Unlock()
+	mc.registerType(obj, "")
+	mc.l.Unlock()
 
-	mc.RegistryBeforeStartHandler(func(ctx context.Context, cli api.MingleClient) error {
+	return mc.registerHandler(func(ctx context.Context, cli api.MingleClient) error {
+		if !configuration.MatchSelector(cli.GetClusterCfgInfo(), selector) {
+			return nil
+		}
 		err := cli.AddResourceEventHandler(obj, handler)
 		if err != nil {
 			return fmt.Errorf("cluster %s AddResourceEventHandler failed %+v", cli.GetClusterCfgInfo().GetName(), err)
 		}
+		mc.markClusterType(cli, obj)
 		return nil
 	})
-	return nil
 }
 
 // TriggerSync just trigger each mingleclient cache resource without handler
 func (mc *multiClient) TriggerSync(obj rtclient.Object) error {
 	mc.l.Lock()
-	defer mc.l.Unlock()
+	mc.registerType(obj, "")
+	mc.l.Unlock()
 
-	mc.RegistryBeforeStartHandler(func(ctx context.Context, cli api.MingleClient) error {
+	return mc.registerHandler(func(ctx context.Context, cli api.MingleClient) error {
 		_, err := cli.GetInformer(obj)
 		if err != nil {
 			return fmt.Errorf("cluster %s TriggerSync failed %+v", cli.GetClusterCfgInfo().GetName(), err)
 		}
+		mc.markClusterType(cli, obj)
 		return nil
 	})
-	return nil
 }
 
-// SetIndexField loop each mingleclient invoke SetIndexField
+// SetIndexField loop each mingleclient invoke SetIndexField, must be invoked before Start,
+// the started informers of running clusters can't add indexers
 func (mc *multiClient) SetIndexField(obj rtclient.Object, field string, extractValue rtclient.IndexerFunc) error {
+	if atomic.LoadInt32(&mc.started) == 1 {
+		return fmt.Errorf("SetIndexField %s must be invoked before multiclient start", field)
+	}
+
 	mc.l.Lock()
-	defer mc.l.Unlock()
+	mc.registerType(obj, field)
+	mc.l.Unlock()
 
-	mc.RegistryBeforeStartHandler(func(ctx context.Context, cli api.MingleClient) error {
+	return mc.registerHandler(func(ctx context.Context, cli api.MingleClient) error {
 		err := cli.SetIndexField(obj, field, extractValue)
 		if err != nil {
 			return fmt.Errorf("cluster %s SetIndexField failed %+v", cli.GetClusterCfgInfo().GetName(), err)
 		}
+		mc.markClusterType(cli, obj)
 		return nil
 	})
-	return nil
 }
 
 // Watch takes events provided by a Source and uses the EventHandler to
@@ -65,10 +86,15 @@ func (mc *multiClient) SetIndexField(obj rtclient.Object, field string, extractV
 // they are given to the EventHandler.  Events will be passed to the
 // EventHandler if all provided Predicates evaluate to true.
 func (mc *multiClient) Watch(obj rtclient.Object, queue api.WorkQueue, evtHandler api.EventHandler, predicates ...api.Predicate) error {
+	return mc.WatchWithSelector(nil, obj, queue, evtHandler, predicates...)
+}
+
+// WatchWithSelector implements SelectorClient
+func (mc *multiClient) WatchWithSelector(selector labels.Selector, obj rtclient.Object, queue api.WorkQueue, evtHandler api.EventHandler, predicates ...api.Predicate) error {
 	if queue == nil {
 		return errors.New("api.WorkQueue is nil")
 	}
-	err := mc.AddResourceEventHandler(obj, handler.NewResourceEventHandler(queue, evtHandler, predicates...))
+	err := mc.AddResourceEventHandlerWithSelector(selector, obj, handler.NewResourceEventHandler(queue, evtHandler, predicates...))
 	if err != nil {
 		return fmt.Errorf("Watch resource failed %+v", err)
 	}
@@ -82,6 +108,10 @@ func (mc *multiClient) HasSynced() bool {
 		klog.Warningln("MultiClient not start, HasSynced return false.")
 		return false
 	}
+	if !mc.IsLeader() {
+		// follower not start any cluster
+		return false
+	}
 
 	mc.l.Lock()
 	defer mc.l.Unlock()
@@ -145,6 +175,44 @@ func (mc *multiClient) GetAllConnected() []api.MingleClient {
 	return list
 }
 
+// registerHandler registry BeforeStartHandle for the new clusters and invoke it with the running clusters,
+// returns aggregated errors of the running clusters
+func (mc *multiClient) registerHandler(handler api.BeforeStartHandle) error {
+	mc.l.Lock()
+	mc.RegistryBeforeStartHandler(handler)
+	ctx, running := mc.runningClients()
+	mc.l.Unlock()
+
+	return invokeWithClients(ctx, running, handler)
+}
+
+// runningClients returns context and running clusters sorted by name, must hold lock
+func (mc *multiClient) runningClients() (context.Context, []api.MingleClient) {
+	ctx := mc.ctx
+	if ctx == nil {
+		ctx = context.TODO()
+	}
+	running := make([]api.MingleClient, 0, len(mc.MingleClientMap))
+	for _, cli := range mc.MingleClientMap {
+		running = append(running, cli)
+	}
+	sort.Slice(running, func(i, j int) bool {
+		return running[i].GetClusterCfgInfo().GetName() < running[j].GetClusterCfgInfo().GetName()
+	})
+	return ctx, running
+}
+
+// invokeWithClients invoke handler without lock, GetInformer may block until cache synced
+func invokeWithClients(ctx context.Context, clients []api.MingleClient, handler api.BeforeStartHandle) error {
+	var errs []error
+	for _, cli := range clients {
+		if err := handler(ctx, cli); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
 // RegistryBeforeStartHandler registry BeforeStartHandle
 func (mc *multiClient) RegistryBeforeStartHandler(handler api.BeforeStartHandle) {
 	mc.BeforStartHandleList = append(mc.BeforStartHandleList, handler)
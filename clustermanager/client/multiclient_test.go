@@ -3,6 +3,7 @@ package client
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -11,7 +12,10 @@ import (
 	"github.com/symcn/pkg/clustermanager/predicate"
 	"github.com/symcn/pkg/clustermanager/workqueue"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ktypes "k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	rtcache "sigs.k8s.io/controller-runtime/pkg/cache"
 	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -336,3 +340,152 @@ func (t *mockEventHandler) Delete(obj rtclient.Object, queue api.WorkQueue) {
 
 func (t *mockEventHandler) Generic(obj rtclient.Object, queue api.WorkQueue) {
 }
+
+func TestLateBinding(t *testing.T) {
+	var invoked []string
+	buildFakeClient := func(name string, getInformerErr error) *FakeClient {
+		return &FakeClient{
+			ClusterCfg: configuration.NewFakeClusterCfgInfo("", api.KubeConfigTypeRawString, "", name),
+			GetInformerFunc: func(obj rtclient.Object) (rtcache.Informer, error) {
+				invoked = append(invoked, name)
+				return nil, getInformerErr
+			},
+		}
+	}
+
+	mc := &multiClient{
+		MingleClientMap: map[string]api.MingleClient{
+			"cluster-1": buildFakeClient("cluster-1", nil),
+			"cluster-2": buildFakeClient("cluster-2", fmt.Errorf("get informer failed")),
+		},
+	}
+	atomic.StoreInt32(&mc.started, 1)
+
+	err := mc.TriggerSync(&corev1.Pod{})
+	if err == nil {
+		t.Error("cluster-2 TriggerSync failed, should return error")
+	}
+	if len(invoked) != 2 {
+		t.Errorf("running clusters should be invoked immediately, but got %v", invoked)
+	}
+	if len(mc.BeforStartHandleList) != 1 {
+		t.Fatalf("handler should be registered for new clusters, but got %d", len(mc.BeforStartHandleList))
+	}
+
+	// new cluster invoked before start
+	invoked = nil
+	if err = start(context.TODO(), buildFakeClient("cluster-3", nil), mc.BeforStartHandleList); err != nil {
+		t.Fatal(err)
+	}
+	if len(invoked) != 1 || invoked[0] != "cluster-3" {
+		t.Errorf("new cluster should be invoked, but got %v", invoked)
+	}
+
+	// started informers can't add indexers
+	err = mc.SetIndexField(&corev1.Pod{}, "spec.nodeName", func(o rtclient.Object) []string { return nil })
+	if err == nil || len(mc.BeforStartHandleList) != 1 {
+		t.Errorf("SetIndexField after start should be rejected, but got %+v with %d handlers", err, len(mc.BeforStartHandleList))
+	}
+}
+
+// notifyConfiguration push change events with channel
+type notifyConfiguration struct {
+	*configuration.FakeConfiguration
+	ch chan configuration.ClusterCfgEvent
+}
+
+func (n *notifyConfiguration) Notify(ctx context.Context) (<-chan configuration.ClusterCfgEvent, error) {
+	return n.ch, nil
+}
+
+func TestNotifyClusterCfg(t *testing.T) {
+	var names atomic.Value
+	names.Store([]string{"cluster-1"})
+	cfgManager := &notifyConfiguration{
+		FakeConfiguration: &configuration.FakeConfiguration{
+			GetAllFunc: func() ([]api.ClusterCfgInfo, error) {
+				list := []api.ClusterCfgInfo{}
+				for _, name := range names.Load().([]string) {
+					list = append(list, configuration.NewFakeClusterCfgInfo("", api.KubeConfigTypeRawString, "", name))
+				}
+				return list, nil
+			},
+		},
+		ch: make(chan configuration.ClusterCfgEvent, 10),
+	}
+
+	mcc := NewMultiClientConfig()
+	// polling disabled, just rebuild with notification
+	mcc.FetchInterval = 0
+	mcc.ClusterCfgManager = cfgManager
+	mcc.BuildClientFunc = NewFackeClient
+	cc, err := Complete(mcc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc, err := cc.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	stopped := make(chan struct{})
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+	go func() {
+		defer close(stopped)
+		mc.Start(ctx)
+	}()
+	time.Sleep(time.Millisecond * 50)
+	if len(mc.GetAll()) != 1 {
+		t.Fatalf("expect 1 cluster, but got %d", len(mc.GetAll()))
+	}
+
+	names.Store([]string{"cluster-1", "cluster-2"})
+	cfgManager.ch <- configuration.ClusterCfgEvent{Type: configuration.ClusterCfgAdded, Name: "cluster-2"}
+	time.Sleep(time.Millisecond * 50)
+	if _, err = mc.GetWithName("cluster-2"); err != nil {
+		t.Errorf("cluster-2 should be added with notification, but got %+v", err)
+	}
+}
+
+func TestCompleteDefaultClusterCfgSource(t *testing.T) {
+	kubeInterface := kubefake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: defaultKubeconfigNamespace, Name: "cluster-1"},
+		Type:       corev1.SecretTypeOpaque,
+		Data:       map[string][]byte{defaultKubeconfigDataKey: []byte("kubeconfig")},
+	})
+
+	mcc := NewMultiClientConfig()
+	mcc.ManagerKubeInterface = kubeInterface
+	mcc.DefaultClusterCfgSource = ClusterCfgSourceSecret
+	cc, err := Complete(mcc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := cc.ClusterCfgManager.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].GetName() != "cluster-1" {
+		t.Errorf("expect cluster-1 from secret, but got %+v", list)
+	}
+
+	mcc = NewMultiClientConfig()
+	mcc.ManagerKubeInterface = kubeInterface
+	mcc.DefaultClusterCfgSource = "unknown"
+	if _, err = Complete(mcc); err == nil {
+		t.Error("unknown source should be error")
+	}
+}
+
+func TestCompleteDefaultClusterCfgContextExpand(t *testing.T) {
+	mcc := NewMultiClientConfig()
+	mcc.ManagerKubeInterface = kubefake.NewSimpleClientset()
+	mcc.DefaultClusterCfgContextExpand = &configuration.ContextExpandOptions{Regexp: "("}
+	if _, err := Complete(mcc); err == nil {
+		t.Error("invalid context expand regexp should be error")
+	}
+}
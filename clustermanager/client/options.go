@@ -2,13 +2,17 @@ package client
 
 import (
 	"crypto/tls"
+	"fmt"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/symcn/api"
 	"github.com/symcn/pkg/clustermanager/configuration"
+	"github.com/symcn/pkg/clustermanager/sharding"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
 )
 
 var (
@@ -17,6 +21,9 @@ var (
 	defaultExecTimeout         = time.Second * 5
 	defaultAutoFetchInterval   = time.Minute * 5
 
+	defaultGracefulShutdownTimeout = time.Second * 30
+	inflightCheckInterval          = time.Millisecond * 10
+
 	defaultManagerClusterName  = "symcn-manager"
 	defaultKubeconfigNamespace = "default"
 	defaultKubeconfigLabel     = map[string]string{}
@@ -34,6 +41,8 @@ var (
 	ErrClientNotExist = "cluster [%s] not exist"
 	// ErrClientNotConnected client disconnected
 	ErrClientNotConnected = "cluster [%s] disconnected"
+	// ErrShutdownTimeout client shutdown timeout
+	ErrShutdownTimeout = "cluster [%s] shutdown timeout %s"
 )
 
 // Options options
@@ -49,6 +58,7 @@ type Options struct {
 	SyncPeriod              time.Duration
 	HealthCheckInterval     time.Duration
 	ExecTimeout             time.Duration
+	GracefulShutdownTimeout time.Duration
 	UserAgent               string
 	QPS                     int
 	Burst                   int
@@ -75,11 +85,52 @@ type WebhookOptions struct {
 	TLSOpts []func(*tls.Config)
 }
 
+// ClusterCfgSourceType the kind of default cluster configuration manager
+type ClusterCfgSourceType string
+
+// ClusterCfgSourceConfigMap kubeconfig stored in ConfigMaps
+// ClusterCfgSourceSecret kubeconfig stored in Secrets
+const (
+	ClusterCfgSourceConfigMap ClusterCfgSourceType = "ConfigMap"
+	ClusterCfgSourceSecret    ClusterCfgSourceType = "Secret"
+)
+
 type MultiClientConfig struct {
 	*Options
+	// DefaultClusterCfgSource build ClusterCfgManager with it when empty, default ClusterCfgSourceConfigMap
+	DefaultClusterCfgSource ClusterCfgSourceType
+	// DefaultClusterCfgContextExpand expand each context of the default source kubeconfig into one cluster when not nil
+	DefaultClusterCfgContextExpand *configuration.ContextExpandOptions
+	// FetchInterval polling interval of ClusterCfgManager, used as resync when it implements configuration.ClusterCfgNotifier
 	FetchInterval     time.Duration
 	ClusterCfgManager api.ClusterConfigurationManager
 	BuildClientFunc   BuildClientFunc
+
+	// RetryBaseDelay first retry delay of the cluster failed to build or start, doubled with each attempt
+	RetryBaseDelay time.Duration
+	// RetryMaxDelay max retry delay of the cluster failed to build or start
+	RetryMaxDelay time.Duration
+
+	// ValidateClusterCfg validate kubeconfig, context, certificates and credential before build client,
+	// the invalid cluster is pending with ReasonValidationFailed
+	ValidateClusterCfg bool
+
+	// ClusterStatusReportInterval write the observed state of clusters back to ClusterCfgManager
+	// which implements configuration.ClusterStatusReporter, disabled when 0
+	ClusterStatusReportInterval time.Duration
+
+	// ManagerClusterCfg manager cluster configuration, default use ~/.kube/config or Kubernetes cluster internal config
+	ManagerClusterCfg api.ClusterCfgInfo
+	// ManagerKubeInterface manager cluster Kubernetes interface, build with ManagerClusterCfg when empty
+	ManagerKubeInterface kubernetes.Interface
+
+	// MultiClusterLeaderElection leader election with the whole multiclient,
+	// the Lease lives in the manager cluster.
+	MultiClusterLeaderElection *LeaderElectionConfig
+
+	// Sharding shard member clusters across replicas with consistent hashing,
+	// each replica registers a heartbeat Lease in the manager cluster.
+	Sharding *sharding.Config
 }
 
 type completeConfig struct {
@@ -95,6 +146,8 @@ func NewMultiClientConfig() *MultiClientConfig {
 		Options:         DefaultOptions(),
 		FetchInterval:   defaultAutoFetchInterval,
 		BuildClientFunc: BuildNormalClient,
+		RetryBaseDelay:  defaultRetryBaseDelay,
+		RetryMaxDelay:   defaultRetryMaxDelay,
 	}
 
 	return mcc
@@ -111,32 +164,109 @@ func Complete(mcc *MultiClientConfig) (*CompletedConfig, error) {
 		clientgoscheme.AddToScheme(cc.MultiClientConfig.Scheme)
 	}
 
+	if mcc.BuildClientFunc == nil {
+		mcc.BuildClientFunc = BuildNormalClient
+	}
+
+	// check multiclient leader election
+	if err := cc.completeLeaderElection(); err != nil {
+		return nil, err
+	}
+
+	// check multiclient sharding
+	if err := cc.completeSharding(); err != nil {
+		return nil, err
+	}
+
 	// check cluster configuration manager
 	if cc.MultiClientConfig.ClusterCfgManager != nil {
 		return cc, nil
 	}
 
 	// build default cluster configuration manager with configmap
-	cli, err := NewMingleClient(configuration.BuildDefaultClusterCfgInfo(defaultManagerClusterName), DefaultOptions())
+	kubeInterface, err := cc.managerKubeInterface()
 	if err != nil {
 		return nil, err
 	}
 
-	if mcc.BuildClientFunc == nil {
-		mcc.BuildClientFunc = BuildNormalClient
+	switch cc.DefaultClusterCfgSource {
+	case ClusterCfgSourceConfigMap, "":
+		cc.MultiClientConfig.ClusterCfgManager = configuration.NewClusterCfgManagerWithCM(
+			kubeInterface,
+			defaultKubeconfigNamespace,
+			defaultKubeconfigLabel,
+			defaultKubeconfigDataKey,
+			defaultKubeconfigStatusKey,
+		)
+	case ClusterCfgSourceSecret:
+		cc.MultiClientConfig.ClusterCfgManager = configuration.NewClusterCfgManagerWithSecret(
+			kubeInterface,
+			defaultKubeconfigNamespace,
+			defaultKubeconfigLabel,
+			defaultKubeconfigDataKey,
+			defaultKubeconfigStatusKey,
+		)
+	default:
+		return nil, fmt.Errorf("not support default cluster configuration source %s", cc.DefaultClusterCfgSource)
 	}
 
-	cc.MultiClientConfig.ClusterCfgManager = configuration.NewClusterCfgManagerWithCM(
-		cli.GetKubeInterface(),
-		defaultKubeconfigNamespace,
-		defaultKubeconfigLabel,
-		defaultKubeconfigDataKey,
-		defaultKubeconfigStatusKey,
-	)
+	if cc.DefaultClusterCfgContextExpand != nil {
+		cc.MultiClientConfig.ClusterCfgManager, err = configuration.NewClusterCfgManagerWithContextExpand(
+			cc.MultiClientConfig.ClusterCfgManager,
+			cc.DefaultClusterCfgContextExpand,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	return cc, nil
 }
 
+func (cc *CompletedConfig) completeLeaderElection() error {
+	lec := cc.MultiClusterLeaderElection
+	if lec == nil || !lec.Enabled {
+		return nil
+	}
+	if err := lec.complete(); err != nil {
+		return err
+	}
+
+	if cc.Options != nil && cc.Options.LeaderElection {
+		// each member cluster should not run election by itself
+		klog.Warningln("multiclient leader election enabled, disable member cluster leader election.")
+		cc.Options.LeaderElection = false
+	}
+
+	_, err := cc.managerKubeInterface()
+	return err
+}
+
+// managerKubeInterface returns manager cluster Kubernetes interface, build it when empty
+func (cc *CompletedConfig) managerKubeInterface() (kubernetes.Interface, error) {
+	if cc.ManagerKubeInterface != nil {
+		return cc.ManagerKubeInterface, nil
+	}
+
+	if cc.ManagerClusterCfg == nil {
+		cc.ManagerClusterCfg = configuration.BuildDefaultClusterCfgInfo(defaultManagerClusterName)
+	}
+
+	var setKubeRestConfigFnList []api.SetKubeRestConfig
+	if cc.Options != nil {
+		setKubeRestConfigFnList = cc.SetKubeRestConfigFnList
+	}
+	restConfig, err := buildClientCmd(cc.ManagerClusterCfg, setKubeRestConfigFnList)
+	if err != nil {
+		return nil, fmt.Errorf("manager cluster %s build kubernetes failed %+v", cc.ManagerClusterCfg.GetName(), err)
+	}
+	cc.ManagerKubeInterface, err = kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("manager cluster %s build kubernetes interface failed %+v", cc.ManagerClusterCfg.GetName(), err)
+	}
+	return cc.ManagerKubeInterface, nil
+}
+
 // New build multiclient
 func (cc *CompletedConfig) New() (api.MultiMingleClient, error) {
 	mc := &multiClient{
@@ -165,15 +295,16 @@ func DefaultOptions() *Options {
 	scheme := runtime.NewScheme()
 	clientgoscheme.AddToScheme(scheme)
 	return &Options{
-		Scheme:              scheme,
-		LoggerDevMode:       true,
-		LeaderElection:      false,
-		SyncPeriod:          defaultSyncPeriod,
-		HealthCheckInterval: defaultHealthCheckInterval,
-		ExecTimeout:         defaultExecTimeout,
-		UserAgent:           defaultUserAgent,
-		QPS:                 defaultQPS,
-		Burst:               defaultBurst,
+		Scheme:                  scheme,
+		LoggerDevMode:           true,
+		LeaderElection:          false,
+		SyncPeriod:              defaultSyncPeriod,
+		HealthCheckInterval:     defaultHealthCheckInterval,
+		ExecTimeout:             defaultExecTimeout,
+		GracefulShutdownTimeout: defaultGracefulShutdownTimeout,
+		UserAgent:               defaultUserAgent,
+		QPS:                     defaultQPS,
+		Burst:                   defaultBurst,
 	}
 }
 
@@ -3,30 +3,67 @@ package client
 import (
 	"errors"
 	"fmt"
+	"net/http"
 
 	multicluster "github.com/oam-dev/cluster-gateway/pkg/apis/cluster/transport"
 	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/configuration"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 )
 
+// ProxyClientOptions options of proxy client
+type ProxyClientOptions struct {
+	Scheme *runtime.Scheme
+	// Mapper REST mapper used by runtime client, it can be shared by all proxy clients,
+	// build lazy discovery REST mapper when empty.
+	Mapper meta.RESTMapper
+}
+
+// RuntimeClientAccessor get runtime client with error
+type RuntimeClientAccessor interface {
+	// GetRuntimeClientWithError returns controller runtime client, it's concurrency-safe.
+	// The proxy client built by NewProxyGatewayMingleClientWithOptions always returns nil error,
+	// the error is kept for the implementations build runtime client lazily.
+	GetRuntimeClientWithError() (rtclient.Client, error)
+}
+
+// ProxyClientCloser close the connections of proxy client
+type ProxyClientCloser interface {
+	// Close close idle connections, the proxy client should not be used after closed
+	Close()
+}
+
 type proxyClient struct {
 	clusterCfg api.ClusterCfgInfo
 
 	scheme           *runtime.Scheme
+	mapper           meta.RESTMapper
 	kubeRestConfig   *rest.Config
+	httpClient       *http.Client
 	kubeInterface    kubernetes.Interface
 	dynamicInterface dynamic.Interface
 	runtimeInterface rtclient.Client
 }
 
 func NewProxyGatewayMingleClient(clusterCfg api.ClusterCfgInfo, scheme *runtime.Scheme) (api.MingleProxyClient, error) {
+	return NewProxyGatewayMingleClientWithOptions(clusterCfg, &ProxyClientOptions{Scheme: scheme})
+}
+
+// NewProxyGatewayMingleClientWithOptions build proxy client with options
+func NewProxyGatewayMingleClientWithOptions(clusterCfg api.ClusterCfgInfo, opts *ProxyClientOptions) (api.MingleProxyClient, error) {
+	if opts == nil {
+		opts = &ProxyClientOptions{}
+	}
 	pcli := &proxyClient{
 		clusterCfg: clusterCfg,
-		scheme:     scheme,
+		scheme:     opts.Scheme,
+		mapper:     opts.Mapper,
 	}
 
 	// 1. pre check
@@ -56,29 +93,58 @@ func (pc *proxyClient) initialization() error {
 	if err != nil {
 		return fmt.Errorf("proxy cluster %s build kubernetes failed %+v", pc.clusterCfg.GetName(), err)
 	}
-	pc.kubeRestConfig.Wrap(multicluster.NewProxyPathPrependingClusterGatewayRoundTripper(pc.clusterCfg.GetName()).NewRoundTripper)
+	pc.kubeRestConfig.Wrap(multicluster.NewProxyPathPrependingClusterGatewayRoundTripper(configuration.GetClusterOriginalName(pc.clusterCfg)).NewRoundTripper)
 
-	// Step 2. build kubernetes interface
-	pc.kubeInterface, err = kubernetes.NewForConfig(pc.kubeRestConfig)
+	// Step 2. build http client shared by kubernetes and dynamic interface
+	pc.httpClient, err = rest.HTTPClientFor(pc.kubeRestConfig)
+	if err != nil {
+		return fmt.Errorf("proxy cluster %s build http client failed %+v", pc.clusterCfg.GetName(), err)
+	}
+
+	// Step 3. build kubernetes interface
+	pc.kubeInterface, err = kubernetes.NewForConfigAndClient(pc.kubeRestConfig, pc.httpClient)
 	if err != nil {
 		return fmt.Errorf("proxy cluster %s build kubernetes interface failed %+v", pc.clusterCfg.GetName(), err)
 	}
 
-	// Step 3. build dynamic interface
-	pc.dynamicInterface, err = dynamic.NewForConfig(pc.kubeRestConfig)
+	// Step 4. build dynamic interface
+	pc.dynamicInterface, err = dynamic.NewForConfigAndClient(pc.kubeRestConfig, pc.httpClient)
 	if err != nil {
 		return fmt.Errorf("proxy cluster %s build dynamic interface failed %+v", pc.clusterCfg.GetName(), err)
 	}
 
-	// // Step 4. build runtime client use lazy load
-	// pc.runtimeInterface, err = rtclient.New(pc.kubeRestConfig, rtclient.Options{})
-	// if err != nil {
-	//     return fmt.Errorf("proxy cluster %s build runtime client failed %+v", pc.clusterCfg.GetName(), err)
-	// }
+	// Step 5. build runtime client with the shared transport, discovery lazily
+	runtimeConfig := sharedTransportConfig(pc.kubeRestConfig, pc.httpClient)
+	mapper := pc.mapper
+	if mapper == nil {
+		mapper, err = apiutil.NewDynamicRESTMapper(runtimeConfig, apiutil.WithLazyDiscovery)
+		if err != nil {
+			return fmt.Errorf("proxy cluster %s build REST mapper failed %+v", pc.clusterCfg.GetName(), err)
+		}
+	}
+	pc.runtimeInterface, err = rtclient.New(runtimeConfig, rtclient.Options{Scheme: pc.scheme, Mapper: mapper})
+	if err != nil {
+		return fmt.Errorf("proxy cluster %s build runtime client failed %+v", pc.clusterCfg.GetName(), err)
+	}
 
 	return nil
 }
 
+// sharedTransportConfig copy rest config use the transport of http client,
+// the transport already contains TLS, credential and wrappers, so they are cleared
+func sharedTransportConfig(cfg *rest.Config, httpClient *http.Client) *rest.Config {
+	shared := rest.CopyConfig(cfg)
+	shared.Transport = httpClient.Transport
+	shared.WrapTransport = nil
+	shared.TLSClientConfig = rest.TLSClientConfig{}
+	shared.Username, shared.Password = "", ""
+	shared.BearerToken, shared.BearerTokenFile = "", ""
+	shared.AuthProvider, shared.AuthConfigPersister = nil, nil
+	shared.ExecProvider = nil
+	shared.Impersonate = rest.ImpersonationConfig{}
+	return shared
+}
+
 // GetRestConfig return Kubernetes rest Config
 func (pc *proxyClient) GetKubeRestConfig() *rest.Config {
 	return pc.kubeRestConfig
@@ -98,17 +164,23 @@ func (pc *proxyClient) GetDynamicInterface() dynamic.Interface {
 
 // GetRuntimeClient() return controller runtime client
 func (pc *proxyClient) GetRuntimeClient() rtclient.Client {
-	if pc.runtimeInterface == nil {
-		var err error
-		pc.runtimeInterface, err = rtclient.New(pc.kubeRestConfig, rtclient.Options{Scheme: pc.scheme})
-		if err != nil {
-			panic(fmt.Errorf("proxy cluster %s build runtime client failed %+v", pc.clusterCfg.GetName(), err))
-		}
-	}
 	return pc.runtimeInterface
 }
 
+// GetRuntimeClientWithError implements RuntimeClientAccessor, the runtime client is built by constructor
+// which returns the build error, so the error is always nil and kept for RuntimeClientAccessor compatibility.
+func (pc *proxyClient) GetRuntimeClientWithError() (rtclient.Client, error) {
+	return pc.runtimeInterface, nil
+}
+
 // GetClusterCfgInfo returns cluster configuration info
 func (pc *proxyClient) GetClusterCfgInfo() api.ClusterCfgInfo {
 	return pc.clusterCfg
 }
+
+// Close implements ProxyClientCloser
+func (pc *proxyClient) Close() {
+	if pc.httpClient != nil {
+		pc.httpClient.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/configuration"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestProxyClientRuntimeClient(t *testing.T) {
+	t.Run("discovery unreachable returns error", func(t *testing.T) {
+		cli, err := NewProxyGatewayMingleClient(configuration.NewFakeClusterCfgInfo(fakeHubKubeconfig, api.KubeConfigTypeRawString, "", "unreachable"), clientgoscheme.Scheme)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// built eagerly, discovery lazily with the first request
+		if cli.GetRuntimeClient() == nil {
+			t.Fatal("runtime client should be built with proxy client")
+		}
+		if err = cli.GetRuntimeClient().List(context.TODO(), &corev1.PodList{}); err == nil {
+			t.Error("unreachable discovery endpoint must be error")
+		}
+	})
+
+	t.Run("shared REST mapper", func(t *testing.T) {
+		var l sync.Mutex
+		cfgManager := &configuration.FakeConfiguration{
+			GetAllFunc: func() ([]api.ClusterCfgInfo, error) {
+				return []api.ClusterCfgInfo{
+					configuration.NewFakeClusterCfgInfo(fakeHubKubeconfig, api.KubeConfigTypeRawString, "", "1"),
+					configuration.NewFakeClusterCfgInfo(fakeHubKubeconfig, api.KubeConfigTypeRawString, "", "2"),
+				}, nil
+			},
+		}
+		mpc, err := NewMingleProxyClientWithOptions(cfgManager, &ProxyMultiClientOptions{
+			Scheme:          runtime.NewScheme(),
+			HubClusterCfg:   configuration.NewFakeClusterCfgInfo(fakeHubKubeconfig, api.KubeConfigTypeRawString, "", "hub"),
+			ShareRESTMapper: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cliList := mpc.GetAll()
+		if len(cliList) != 2 {
+			t.Fatalf("proxy clients should be %d, but got %d", 2, len(cliList))
+		}
+
+		// concurrency get runtime client, lazy REST mapper not request discovery
+		runtimeClients := map[string]rtclient.Client{}
+		wg := sync.WaitGroup{}
+		for i := 0; i < 10; i++ {
+			for _, cli := range cliList {
+				wg.Add(1)
+				go func(cli api.MingleProxyClient) {
+					defer wg.Done()
+					rc, err := cli.(RuntimeClientAccessor).GetRuntimeClientWithError()
+					if err != nil {
+						t.Error(err)
+						return
+					}
+
+					l.Lock()
+					defer l.Unlock()
+					name := cli.GetClusterCfgInfo().GetName()
+					if old, ok := runtimeClients[name]; ok && old != rc {
+						t.Errorf("cluster %s runtime client should be built once", name)
+					}
+					runtimeClients[name] = rc
+				}(cli)
+			}
+		}
+		wg.Wait()
+
+		if len(runtimeClients) != 2 || runtimeClients["1"].RESTMapper() != runtimeClients["2"].RESTMapper() {
+			t.Error("all proxy clients should share the same REST mapper")
+		}
+	})
+
+	t.Run("share REST mapper without hub cluster", func(t *testing.T) {
+		_, err := NewMingleProxyClientWithOptions(configuration.NewFakeConfiguration(), &ProxyMultiClientOptions{ShareRESTMapper: true})
+		if err == nil {
+			t.Error("share REST mapper without hub cluster must be error")
+		}
+	})
+}
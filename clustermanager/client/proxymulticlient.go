@@ -1,51 +1,264 @@
 package client
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/symcn/api"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 )
 
+// ProxyMultiClientOptions options of proxy multi client
+type ProxyMultiClientOptions struct {
+	Scheme *runtime.Scheme
+	// HubClusterCfg the hub cluster which cluster-gateway installed, used to build shared REST mapper
+	HubClusterCfg api.ClusterCfgInfo
+	// ShareRESTMapper all proxy clients share one lazy REST mapper discovery with the hub cluster,
+	// use it when the hub and managed clusters serve the same API resources.
+	ShareRESTMapper bool
+}
+
+// ProxyClusterEventHandler notify when proxy client added to or removed from cache
+type ProxyClusterEventHandler interface {
+	OnAdd(cli api.MingleProxyClient)
+	OnDelete(cli api.MingleProxyClient)
+}
+
+// ProxyClientLifecycle manage the proxy client cache lifecycle
+type ProxyClientLifecycle interface {
+	// Reconcile sync cache with ClusterConfigurationManager,
+	// evict removed clusters and rebuild modified clusters.
+	Reconcile() error
+
+	// Start reconcile with interval, blocks until the context is cancelled,
+	// all cached clients will be removed before return.
+	Start(ctx context.Context, interval time.Duration)
+
+	// AddProxyClusterEventHandler add handler, OnAdd invoked with cached clients immediately
+	AddProxyClusterEventHandler(handler ProxyClusterEventHandler)
+}
+
+type buildProxyClientFunc func(api.ClusterCfgInfo, *ProxyClientOptions) (api.MingleProxyClient, error)
+
 type proxyMultiClient struct {
 	ccm    api.ClusterConfigurationManager
 	scheme *runtime.Scheme
+	mapper meta.RESTMapper
 	cache  map[string]api.MingleProxyClient
 	sync.Mutex
+
+	// reconcileLock serialize reconcile and handlers invoke
+	reconcileLock   sync.Mutex
+	handlerList     []ProxyClusterEventHandler
+	buildClientFunc buildProxyClientFunc
 }
 
 func NewMingleProxyClient(ccm api.ClusterConfigurationManager, scheme *runtime.Scheme) api.MultiProxyClient {
 	return &proxyMultiClient{
-		ccm:    ccm,
-		scheme: scheme,
-		cache:  make(map[string]api.MingleProxyClient),
+		ccm:             ccm,
+		scheme:          scheme,
+		cache:           make(map[string]api.MingleProxyClient),
+		buildClientFunc: NewProxyGatewayMingleClientWithOptions,
 	}
 }
 
+// NewMingleProxyClientWithOptions build proxy multi client with options
+func NewMingleProxyClientWithOptions(ccm api.ClusterConfigurationManager, opts *ProxyMultiClientOptions) (api.MultiProxyClient, error) {
+	if opts == nil {
+		opts = &ProxyMultiClientOptions{}
+	}
+	pm := &proxyMultiClient{
+		ccm:             ccm,
+		scheme:          opts.Scheme,
+		cache:           make(map[string]api.MingleProxyClient),
+		buildClientFunc: NewProxyGatewayMingleClientWithOptions,
+	}
+
+	if opts.ShareRESTMapper {
+		if opts.HubClusterCfg == nil {
+			return nil, errors.New("share REST mapper must set hub cluster configuration")
+		}
+		restConfig, err := buildClientCmd(opts.HubClusterCfg, nil)
+		if err != nil {
+			return nil, fmt.Errorf("hub cluster %s build kubernetes failed %+v", opts.HubClusterCfg.GetName(), err)
+		}
+		// lazy discovery, not request hub cluster until used
+		pm.mapper, err = apiutil.NewDynamicRESTMapper(restConfig, apiutil.WithLazyDiscovery)
+		if err != nil {
+			return nil, fmt.Errorf("hub cluster %s build REST mapper failed %+v", opts.HubClusterCfg.GetName(), err)
+		}
+	}
+	return pm, nil
+}
+
+// GetAll reconcile cache and returns all proxy clients
 func (pm *proxyMultiClient) GetAll() []api.MingleProxyClient {
-	clsList, err := pm.ccm.GetAll()
+	cliList, err := pm.reconcile()
 	if err != nil {
-		klog.Warningf("Not found proxy client config")
+		klog.Warningf("Not found proxy client config: %+v", err)
 		return nil
 	}
+	return cliList
+}
+
+// Reconcile implements ProxyClientLifecycle
+func (pm *proxyMultiClient) Reconcile() error {
+	_, err := pm.reconcile()
+	return err
+}
+
+// Start implements ProxyClientLifecycle
+func (pm *proxyMultiClient) Start(ctx context.Context, interval time.Duration) {
+	if err := pm.Reconcile(); err != nil {
+		klog.Errorf("Reconcile proxy client failed: %+v", err)
+	}
+
+	if interval > 0 {
+		timer := time.NewTicker(interval)
+		defer timer.Stop()
 
+	loop:
+		for {
+			select {
+			case <-timer.C:
+				if err := pm.Reconcile(); err != nil {
+					klog.Errorf("Reconcile proxy client failed: %+v", err)
+				}
+			case <-ctx.Done():
+				break loop
+			}
+		}
+	} else {
+		<-ctx.Done()
+	}
+
+	pm.removeAll()
+}
+
+// AddProxyClusterEventHandler implements ProxyClientLifecycle
+func (pm *proxyMultiClient) AddProxyClusterEventHandler(handler ProxyClusterEventHandler) {
+	pm.reconcileLock.Lock()
+	defer pm.reconcileLock.Unlock()
+
+	pm.Lock()
+	cliList := make([]api.MingleProxyClient, 0, len(pm.cache))
+	for _, cli := range pm.cache {
+		cliList = append(cliList, cli)
+	}
+	pm.handlerList = append(pm.handlerList, handler)
+	pm.Unlock()
+
+	for _, cli := range cliList {
+		handler.OnAdd(cli)
+	}
+}
+
+// reconcile rebuild cache with ClusterConfigurationManager, returns the clients with the same order.
+func (pm *proxyMultiClient) reconcile() ([]api.MingleProxyClient, error) {
+	clsList, err := pm.ccm.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("get all proxy cluster info failed %+v", err)
+	}
+
+	pm.reconcileLock.Lock()
+	defer pm.reconcileLock.Unlock()
+
+	pm.Lock()
+	current := make(map[string]api.MingleProxyClient, len(pm.cache))
+	for name, cli := range pm.cache {
+		current[name] = cli
+	}
+	pm.Unlock()
+
+	fresh := make(map[string]api.MingleProxyClient, len(clsList))
 	cliList := make([]api.MingleProxyClient, 0, len(clsList))
+	addList := []api.MingleProxyClient{}
+	removeList := []api.MingleProxyClient{}
 	for _, cls := range clsList {
+		cli, exist := current[cls.GetName()]
+		if exist && sameClusterCfgInfo(cli.GetClusterCfgInfo(), cls) {
+			fresh[cls.GetName()] = cli
+			cliList = append(cliList, cli)
+			continue
+		}
 
-		cli, ok := pm.GetProxyClientFromCache(cls.GetName())
-		if !ok {
-			cli, err = NewProxyGatewayMingleClient(cls, pm.scheme)
-			if err != nil {
-				klog.Errorf("Build proxy client error: %+v, ignore.", err.Error())
-				continue
+		newCli, err := pm.buildClientFunc(cls, &ProxyClientOptions{Scheme: pm.scheme, Mapper: pm.mapper})
+		if err != nil {
+			klog.Errorf("Build proxy client error: %+v, ignore.", err.Error())
+			if exist {
+				// keep old client, rebuild next time
+				fresh[cls.GetName()] = cli
+				cliList = append(cliList, cli)
 			}
-			pm.PutProxyClientToCache(cls.GetName(), cli)
+			continue
 		}
-		cliList = append(cliList, cli)
+
+		if exist {
+			// configuration modified, should close old client
+			klog.Infof("Proxy cluster %s configuration modified, rebuild client.", cls.GetName())
+			removeList = append(removeList, cli)
+		}
+		fresh[cls.GetName()] = newCli
+		cliList = append(cliList, newCli)
+		addList = append(addList, newCli)
 	}
 
-	return cliList
+	// remove unexpect cluster
+	for name, cli := range current {
+		if _, ok := fresh[name]; !ok {
+			klog.Infof("Proxy cluster %s removed, evict client.", name)
+			removeList = append(removeList, cli)
+		}
+	}
+
+	pm.Lock()
+	pm.cache = fresh
+	pm.Unlock()
+
+	pm.notify(removeList, addList)
+	return cliList, nil
+}
+
+// removeAll remove all cached clients
+func (pm *proxyMultiClient) removeAll() {
+	pm.reconcileLock.Lock()
+	defer pm.reconcileLock.Unlock()
+
+	pm.Lock()
+	removeList := make([]api.MingleProxyClient, 0, len(pm.cache))
+	for _, cli := range pm.cache {
+		removeList = append(removeList, cli)
+	}
+	pm.cache = make(map[string]api.MingleProxyClient)
+	pm.Unlock()
+
+	pm.notify(removeList, nil)
+}
+
+// notify invoke handlers and close removed clients, must hold reconcileLock
+func (pm *proxyMultiClient) notify(removeList, addList []api.MingleProxyClient) {
+	pm.Lock()
+	handlerList := make([]ProxyClusterEventHandler, len(pm.handlerList))
+	copy(handlerList, pm.handlerList)
+	pm.Unlock()
+
+	for _, cli := range removeList {
+		for _, handler := range handlerList {
+			handler.OnDelete(cli)
+		}
+		closeProxyClient(cli)
+	}
+	for _, cli := range addList {
+		for _, handler := range handlerList {
+			handler.OnAdd(cli)
+		}
+	}
 }
 
 func (pm *proxyMultiClient) GetProxyClientFromCache(clsName string) (api.MingleProxyClient, bool) {
@@ -63,9 +276,14 @@ func (pm *proxyMultiClient) PutProxyClientToCache(clsName string, cli api.Mingle
 	if len(pm.cache) == 0 {
 		pm.cache = make(map[string]api.MingleProxyClient)
 	}
-	// // TODO: close old client
-	// oldCli, ok := pm.cache[clsName]
-	// if ok {
-	// }
+	if oldCli, ok := pm.cache[clsName]; ok && oldCli != cli {
+		closeProxyClient(oldCli)
+	}
 	pm.cache[clsName] = cli
 }
+
+func closeProxyClient(cli api.MingleProxyClient) {
+	if closer, ok := cli.(ProxyClientCloser); ok {
+		closer.Close()
+	}
+}
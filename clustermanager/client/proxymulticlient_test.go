@@ -0,0 +1,146 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/configuration"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var fakeHubKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: https://127.0.0.1:6443
+  name: hub
+contexts:
+- context:
+    cluster: hub
+    user: hub
+  name: hub
+current-context: hub
+users:
+- name: hub
+  user:
+    token: fake
+`
+
+type closeRecordProxyClient struct {
+	api.MingleProxyClient
+	closed int32
+}
+
+func (c *closeRecordProxyClient) Close() {
+	atomic.AddInt32(&c.closed, 1)
+}
+
+type mockProxyClusterEventHandler struct {
+	l       sync.Mutex
+	added   []string
+	deleted []string
+}
+
+func (h *mockProxyClusterEventHandler) OnAdd(cli api.MingleProxyClient) {
+	h.l.Lock()
+	defer h.l.Unlock()
+	h.added = append(h.added, cli.GetClusterCfgInfo().GetName())
+}
+
+func (h *mockProxyClusterEventHandler) OnDelete(cli api.MingleProxyClient) {
+	h.l.Lock()
+	defer h.l.Unlock()
+	h.deleted = append(h.deleted, cli.GetClusterCfgInfo().GetName())
+}
+
+func (h *mockProxyClusterEventHandler) count() (int, int) {
+	h.l.Lock()
+	defer h.l.Unlock()
+	return len(h.added), len(h.deleted)
+}
+
+func TestProxyMultiClientReconcile(t *testing.T) {
+	var l sync.Mutex
+	clusters := map[string]string{"1": "", "2": "", "3": ""}
+	cfgManager := &configuration.FakeConfiguration{
+		GetAllFunc: func() ([]api.ClusterCfgInfo, error) {
+			l.Lock()
+			defer l.Unlock()
+			list := []api.ClusterCfgInfo{}
+			for name, kubecontext := range clusters {
+				list = append(list, configuration.NewFakeClusterCfgInfo(fakeHubKubeconfig, api.KubeConfigTypeRawString, kubecontext, name))
+			}
+			return list, nil
+		},
+	}
+
+	var built int32
+	pm := NewMingleProxyClient(cfgManager, runtime.NewScheme()).(*proxyMultiClient)
+	pm.buildClientFunc = func(info api.ClusterCfgInfo, opts *ProxyClientOptions) (api.MingleProxyClient, error) {
+		atomic.AddInt32(&built, 1)
+		cli, err := NewProxyGatewayMingleClientWithOptions(info, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &closeRecordProxyClient{MingleProxyClient: cli}, nil
+	}
+	handler := &mockProxyClusterEventHandler{}
+	pm.AddProxyClusterEventHandler(handler)
+
+	if len(pm.GetAll()) != 3 {
+		t.Fatalf("proxy clients should be %d, but got %d", 3, len(pm.GetAll()))
+	}
+	if atomic.LoadInt32(&built) != 3 {
+		t.Errorf("unchanged clusters should be cached, but built %d times", atomic.LoadInt32(&built))
+	}
+	if added, deleted := handler.count(); added != 3 || deleted != 0 {
+		t.Errorf("should add 3 and delete 0, but got %d and %d", added, deleted)
+	}
+
+	removed, _ := pm.GetProxyClientFromCache("3")
+	modified, _ := pm.GetProxyClientFromCache("2")
+	l.Lock()
+	delete(clusters, "3")
+	clusters["2"] = "hub"
+	l.Unlock()
+
+	if err := pm.Reconcile(); err != nil {
+		t.Fatal(err)
+	}
+	if len(pm.GetAll()) != 2 {
+		t.Fatalf("proxy clients should be %d, but got %d", 2, len(pm.GetAll()))
+	}
+	if _, ok := pm.GetProxyClientFromCache("3"); ok {
+		t.Error("removed cluster should be evicted")
+	}
+	if cli, _ := pm.GetProxyClientFromCache("2"); cli == modified || cli.GetClusterCfgInfo().GetKubeContext() != "hub" {
+		t.Error("modified cluster should be rebuilt")
+	}
+	if atomic.LoadInt32(&removed.(*closeRecordProxyClient).closed) != 1 || atomic.LoadInt32(&modified.(*closeRecordProxyClient).closed) != 1 {
+		t.Error("removed and modified clients should be closed")
+	}
+	if added, deleted := handler.count(); added != 4 || deleted != 2 {
+		t.Errorf("should add 4 and delete 2, but got %d and %d", added, deleted)
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	stopped := make(chan struct{})
+	go func() {
+		pm.Start(ctx, time.Millisecond*50)
+		close(stopped)
+	}()
+	time.Sleep(time.Millisecond * 100)
+	cancel()
+	<-stopped
+
+	if _, ok := pm.GetProxyClientFromCache("1"); ok {
+		t.Error("all clients should be removed after stopped")
+	}
+	if added, deleted := handler.count(); added != 4 || deleted != 4 {
+		t.Errorf("should add 4 and delete 4, but got %d and %d", added, deleted)
+	}
+}
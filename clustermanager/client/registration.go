@@ -0,0 +1,237 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/handler"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	rtcache "sigs.k8s.io/controller-runtime/pkg/cache"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Registration handle of the registered event handler
+type Registration interface {
+	// Remove remove the handler from all clusters, the new clusters will not be registered
+	Remove() error
+
+	// RemoveFromCluster remove the handler from the cluster, the cluster will not be registered when rebuilt
+	RemoveFromCluster(name string) error
+}
+
+// RegistrationClient optional interface of MingleClient and MultiMingleClient,
+// returns Registration so that the handler can be removed
+type RegistrationClient interface {
+	// AddResourceEventHandlerWithRegistration AddResourceEventHandler and returns Registration
+	AddResourceEventHandlerWithRegistration(obj rtclient.Object, handler cache.ResourceEventHandler) (Registration, error)
+
+	// WatchWithRegistration Watch and returns Registration
+	WatchWithRegistration(obj rtclient.Object, queue api.WorkQueue, evtHandler api.EventHandler, predicates ...api.Predicate) (Registration, error)
+}
+
+// AddResourceEventHandlerWithRegistration implements RegistrationClient
+func (c *client) AddResourceEventHandlerWithRegistration(obj rtclient.Object, handler cache.ResourceEventHandler) (Registration, error) {
+	informer, err := c.GetInformer(obj)
+	if err != nil {
+		return nil, err
+	}
+	reg, err := informer.AddEventHandler(&inflightEventHandler{c: c, handler: handler})
+	if err != nil {
+		return nil, err
+	}
+	return &clientRegistration{name: c.clusterCfg.GetName(), informer: informer, reg: reg}, nil
+}
+
+// WatchWithRegistration implements RegistrationClient
+func (c *client) WatchWithRegistration(obj rtclient.Object, queue api.WorkQueue, evtHandler api.EventHandler, predicates ...api.Predicate) (Registration, error) {
+	if queue == nil {
+		return nil, errors.New("api.WorkQueue is nil")
+	}
+	return c.AddResourceEventHandlerWithRegistration(obj, handler.NewResourceEventHandler(queue, evtHandler, predicates...))
+}
+
+// clientRegistration the handler registered with one cluster informer
+type clientRegistration struct {
+	name     string
+	informer rtcache.Informer
+	reg      cache.ResourceEventHandlerRegistration
+}
+
+func (r *clientRegistration) Remove() error {
+	return r.informer.RemoveEventHandler(r.reg)
+}
+
+func (r *clientRegistration) RemoveFromCluster(name string) error {
+	if name != r.name {
+		return fmt.Errorf(ErrClientNotExist, name)
+	}
+	return r.Remove()
+}
+
+// AddResourceEventHandlerWithRegistration implements RegistrationClient,
+// the running clusters registered immediately and the new clusters registered before start
+func (mc *multiClient) AddResourceEventHandlerWithRegistration(obj rtclient.Object, handler cache.ResourceEventHandler) (Registration, error) {
+	r := &multiRegistration{
+		mc:       mc,
+		regs:     map[string]Registration{},
+		excluded: sets.NewString(),
+	}
+	r.handle = func(ctx context.Context, cli api.MingleClient) error {
+		name := cli.GetClusterCfgInfo().GetName()
+		r.l.Lock()
+		defer r.l.Unlock()
+		if r.removed || r.excluded.Has(name) {
+			return nil
+		}
+
+		rc, ok := cli.(RegistrationClient)
+		if !ok {
+			klog.Warningf("cluster %s not support remove handler, handler will be kept until cluster stopped", name)
+			if err := cli.AddResourceEventHandler(obj, handler); err != nil {
+				return fmt.Errorf("cluster %s AddResourceEventHandler failed %+v", name, err)
+			}
+			mc.markClusterType(cli, obj)
+			return nil
+		}
+		reg, err := rc.AddResourceEventHandlerWithRegistration(obj, handler)
+		if err != nil {
+			return fmt.Errorf("cluster %s AddResourceEventHandler failed %+v", name, err)
+		}
+		r.regs[name] = reg
+		mc.markClusterType(cli, obj)
+		return nil
+	}
+
+	mc.l.Lock()
+	mc.registerType(obj, "")
+	mc.registrationList = append(mc.registrationList, r)
+	ctx, running := mc.runningClients()
+	mc.l.Unlock()
+
+	return r, invokeWithClients(ctx, running, r.handle)
+}
+
+// WatchWithRegistration implements RegistrationClient
+func (mc *multiClient) WatchWithRegistration(obj rtclient.Object, queue api.WorkQueue, evtHandler api.EventHandler, predicates ...api.Predicate) (Registration, error) {
+	if queue == nil {
+		return nil, errors.New("api.WorkQueue is nil")
+	}
+	reg, err := mc.AddResourceEventHandlerWithRegistration(obj, handler.NewResourceEventHandler(queue, evtHandler, predicates...))
+	if err != nil {
+		return reg, fmt.Errorf("Watch resource failed %+v", err)
+	}
+	return reg, nil
+}
+
+// beforeStartHandlers returns BeforStartHandleList and the handlers of registrations, must hold lock
+func (mc *multiClient) beforeStartHandlers() []api.BeforeStartHandle {
+	if len(mc.registrationList) == 0 {
+		return mc.BeforStartHandleList
+	}
+	list := make([]api.BeforeStartHandle, 0, len(mc.BeforStartHandleList)+len(mc.registrationList))
+	list = append(list, mc.BeforStartHandleList...)
+	for _, r := range mc.registrationList {
+		list = append(list, r.handle)
+	}
+	return list
+}
+
+// handlerSnapshot the registered handlers at some time
+type handlerSnapshot struct {
+	beforeStartLen int
+	registrations  map[*multiRegistration]struct{}
+}
+
+// snapshotHandlers must hold lock
+func (mc *multiClient) snapshotHandlers() handlerSnapshot {
+	snapshot := handlerSnapshot{
+		beforeStartLen: len(mc.BeforStartHandleList),
+		registrations:  make(map[*multiRegistration]struct{}, len(mc.registrationList)),
+	}
+	for _, r := range mc.registrationList {
+		snapshot.registrations[r] = struct{}{}
+	}
+	return snapshot
+}
+
+// handlersSince returns the handlers registered after snapshot, must hold lock
+func (mc *multiClient) handlersSince(snapshot handlerSnapshot) []api.BeforeStartHandle {
+	list := []api.BeforeStartHandle{}
+	if snapshot.beforeStartLen < len(mc.BeforStartHandleList) {
+		list = append(list, mc.BeforStartHandleList[snapshot.beforeStartLen:]...)
+	}
+	for _, r := range mc.registrationList {
+		if _, ok := snapshot.registrations[r]; !ok {
+			list = append(list, r.handle)
+		}
+	}
+	return list
+}
+
+func (mc *multiClient) removeRegistration(r *multiRegistration) {
+	mc.l.Lock()
+	defer mc.l.Unlock()
+
+	for i, item := range mc.registrationList {
+		if item == r {
+			mc.registrationList = append(mc.registrationList[:i], mc.registrationList[i+1:]...)
+			return
+		}
+	}
+}
+
+// multiRegistration the handler registered with multi cluster informers
+type multiRegistration struct {
+	mc       *multiClient
+	handle   api.BeforeStartHandle
+	l        sync.Mutex
+	regs     map[string]Registration
+	excluded sets.String
+	removed  bool
+}
+
+func (r *multiRegistration) Remove() error {
+	r.mc.removeRegistration(r)
+
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	r.removed = true
+	names := make([]string, 0, len(r.regs))
+	for name := range r.regs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		if err := r.regs[name].Remove(); err != nil {
+			errs = append(errs, fmt.Errorf("cluster %s remove handler failed %+v", name, err))
+		}
+		delete(r.regs, name)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func (r *multiRegistration) RemoveFromCluster(name string) error {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	r.excluded.Insert(name)
+	reg, ok := r.regs[name]
+	if !ok {
+		return nil
+	}
+	delete(r.regs, name)
+	if err := reg.Remove(); err != nil {
+		return fmt.Errorf("cluster %s remove handler failed %+v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/configuration"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// registrationFakeClient record registered handlers
+type registrationFakeClient struct {
+	*FakeClient
+	handlers map[cache.ResourceEventHandler]bool
+}
+
+type fakeRegistration struct {
+	cli     *registrationFakeClient
+	handler cache.ResourceEventHandler
+}
+
+func (r *fakeRegistration) Remove() error {
+	delete(r.cli.handlers, r.handler)
+	return nil
+}
+
+func (r *fakeRegistration) RemoveFromCluster(name string) error {
+	return r.Remove()
+}
+
+func (f *registrationFakeClient) AddResourceEventHandlerWithRegistration(obj rtclient.Object, handler cache.ResourceEventHandler) (Registration, error) {
+	f.handlers[handler] = true
+	return &fakeRegistration{cli: f, handler: handler}, nil
+}
+
+func (f *registrationFakeClient) WatchWithRegistration(obj rtclient.Object, queue api.WorkQueue, evtHandler api.EventHandler, predicates ...api.Predicate) (Registration, error) {
+	return nil, nil
+}
+
+func TestMultiRegistration(t *testing.T) {
+	buildFakeClient := func(name string) *registrationFakeClient {
+		return &registrationFakeClient{
+			FakeClient: &FakeClient{ClusterCfg: configuration.NewFakeClusterCfgInfo("", api.KubeConfigTypeRawString, "", name)},
+			handlers:   map[cache.ResourceEventHandler]bool{},
+		}
+	}
+	cluster1, cluster2, cluster3 := buildFakeClient("cluster-1"), buildFakeClient("cluster-2"), buildFakeClient("cluster-3")
+	mc := &multiClient{
+		MingleClientMap: map[string]api.MingleClient{"cluster-1": cluster1, "cluster-2": cluster2},
+	}
+
+	handler := &cache.ResourceEventHandlerFuncs{}
+	reg, err := mc.AddResourceEventHandlerWithRegistration(&corev1.Pod{}, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cluster1.handlers[handler] || !cluster2.handlers[handler] {
+		t.Fatal("running clusters should be registered")
+	}
+
+	if err = reg.RemoveFromCluster("cluster-1"); err != nil {
+		t.Fatal(err)
+	}
+	if cluster1.handlers[handler] || !cluster2.handlers[handler] {
+		t.Error("handler should only be removed from cluster-1")
+	}
+
+	// cluster-1 rebuilt and cluster-3 added
+	rebuilt := buildFakeClient("cluster-1")
+	for _, cli := range []api.MingleClient{rebuilt, cluster3} {
+		if err = start(context.TODO(), cli, mc.beforeStartHandlers()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rebuilt.handlers[handler] || !cluster3.handlers[handler] {
+		t.Error("removed cluster should not be registered again, new cluster should be registered")
+	}
+
+	if err = reg.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if cluster2.handlers[handler] || cluster3.handlers[handler] {
+		t.Error("handler should be removed from all clusters")
+	}
+	if len(mc.beforeStartHandlers()) != 0 {
+		t.Error("removed registration should not be invoked with new clusters")
+	}
+}
@@ -0,0 +1,284 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/configuration"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+)
+
+var (
+	defaultRetryBaseDelay     = time.Second * 1
+	defaultRetryMaxDelay      = time.Minute * 2
+	defaultRetryCheckInterval = time.Second * 1
+)
+
+// ReasonBuildFailed build mingle client failed, such as invalid kubeconfig or unreachable API
+// ReasonStartFailed invoke BeforeStartHandle failed
+// ReasonValidationFailed configuration validation failed with MultiClientConfig.ValidateClusterCfg
+const (
+	ReasonBuildFailed      = "BuildFailed"
+	ReasonStartFailed      = "StartFailed"
+	ReasonValidationFailed = "ValidationFailed"
+)
+
+// PendingCluster the cluster failed to build or start, waiting retry with backoff
+type PendingCluster struct {
+	Name           string
+	ClusterCfgInfo api.ClusterCfgInfo
+	Reason         string
+	Message        string
+	Attempts       int
+	LastAttempt    time.Time
+	NextRetry      time.Time
+}
+
+// PendingClusterStatus reports the clusters failed to build or start
+type PendingClusterStatus interface {
+	// GetPendingClusters returns all pending clusters sorted by name
+	GetPendingClusters() []PendingCluster
+}
+
+type clusterBuildError struct {
+	reason string
+	err    error
+}
+
+func (e *clusterBuildError) Error() string {
+	return e.err.Error()
+}
+
+func (e *clusterBuildError) Unwrap() error {
+	return e.err
+}
+
+// GetPendingClusters implements PendingClusterStatus
+func (mc *multiClient) GetPendingClusters() []PendingCluster {
+	mc.l.Lock()
+	defer mc.l.Unlock()
+
+	list := make([]PendingCluster, 0, len(mc.pendingClusterMap))
+	for _, pending := range mc.pendingClusterMap {
+		list = append(list, *pending)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// retryDelay exponential backoff with attempts, capped by RetryMaxDelay
+func (mc *multiClient) retryDelay(attempts int) time.Duration {
+	base, max := defaultRetryBaseDelay, defaultRetryMaxDelay
+	if mc.RetryBaseDelay > 0 {
+		base = mc.RetryBaseDelay
+	}
+	if mc.RetryMaxDelay > 0 {
+		max = mc.RetryMaxDelay
+	}
+
+	delay := base
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+	return delay
+}
+
+// pendingNotDue returns true if the cluster is pending with same configuration and not reach retry time,
+// must hold lock
+func (mc *multiClient) pendingNotDue(info api.ClusterCfgInfo) bool {
+	pending, ok := mc.pendingClusterMap[info.GetName()]
+	if !ok || !sameClusterCfgInfo(pending.ClusterCfgInfo, info) {
+		return false
+	}
+	return time.Now().Before(pending.NextRetry)
+}
+
+// recordPending record build or start failed cluster, must hold lock
+func (mc *multiClient) recordPending(info api.ClusterCfgInfo, err error) {
+	reason := ReasonBuildFailed
+	buildErr := &clusterBuildError{}
+	if errors.As(err, &buildErr) {
+		reason = buildErr.reason
+	}
+
+	if mc.pendingClusterMap == nil {
+		mc.pendingClusterMap = map[string]*PendingCluster{}
+	}
+
+	pending, ok := mc.pendingClusterMap[info.GetName()]
+	if !ok || !sameClusterCfgInfo(pending.ClusterCfgInfo, info) {
+		if ok {
+			getStats().deletePending(pending.Name, pending.Reason, len(mc.pendingClusterMap))
+		}
+		// new pending or configuration modified, reset backoff
+		pending = &PendingCluster{Name: info.GetName()}
+		mc.pendingClusterMap[info.GetName()] = pending
+	} else if pending.Reason != reason {
+		getStats().deletePending(pending.Name, pending.Reason, len(mc.pendingClusterMap))
+	}
+
+	now := time.Now()
+	pending.ClusterCfgInfo = info
+	pending.Reason = reason
+	pending.Message = err.Error()
+	pending.Attempts++
+	pending.LastAttempt = now
+	pending.NextRetry = now.Add(mc.retryDelay(pending.Attempts))
+
+	getStats().setPending(pending.Name, pending.Reason, len(mc.pendingClusterMap))
+	klog.ErrorS(err, "Cluster pending, will retry with backoff.", "clusterName", pending.Name, "reason", reason, "attempts", pending.Attempts, "nextRetry", pending.NextRetry)
+}
+
+// resolvePending remove pending cluster, must hold lock
+func (mc *multiClient) resolvePending(name string) {
+	pending, ok := mc.pendingClusterMap[name]
+	if !ok {
+		return
+	}
+	delete(mc.pendingClusterMap, name)
+	getStats().resolvePending(pending.Name, pending.Reason, len(mc.pendingClusterMap))
+}
+
+// loopRetryPending retry pending clusters independent of FetchInterval
+func (mc *multiClient) loopRetryPending(ctx context.Context, interval time.Duration) {
+	timer := time.NewTicker(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			mc.retryPendingOnce()
+		case <-ctx.Done():
+			return
+		case <-mc.stopCh:
+			return
+		}
+	}
+}
+
+// retryPendingOnce retry the due pending clusters with the latest configuration
+func (mc *multiClient) retryPendingOnce() {
+	mc.l.Lock()
+	if mc.ctx == nil || mc.ctx.Err() != nil {
+		mc.l.Unlock()
+		return
+	}
+	now := time.Now()
+	due := []string{}
+	for name, pending := range mc.pendingClusterMap {
+		if !now.Before(pending.NextRetry) {
+			due = append(due, name)
+		}
+	}
+	mc.l.Unlock()
+	if len(due) == 0 {
+		return
+	}
+
+	// the source may be modified after pending
+	freshList, err := mc.ClusterCfgManager.GetAll()
+	if err != nil {
+		klog.ErrorS(err, "Retry pending clusters get all cluster info failed")
+		return
+	}
+	mc.l.Lock()
+	freshList = mc.filterShardOwned(freshList)
+	mc.l.Unlock()
+
+	freshMap := make(map[string]api.ClusterCfgInfo, len(freshList))
+	for _, info := range freshList {
+		freshMap[info.GetName()] = info
+	}
+	sort.Strings(due)
+	for _, name := range due {
+		mc.retryPending(name, freshMap[name])
+	}
+}
+
+// retryPending build the pending cluster without lock, so the others not blocked by a slow cluster
+func (mc *multiClient) retryPending(name string, fresh api.ClusterCfgInfo) {
+	mc.l.Lock()
+	pending, ok := mc.pendingClusterMap[name]
+	if !ok || time.Now().Before(pending.NextRetry) {
+		// resolved or retrying by others
+		mc.l.Unlock()
+		return
+	}
+	if _, exist := mc.MingleClientMap[name]; exist || fresh == nil {
+		// connected by rebuild, removed from source or not owned by this replica
+		mc.resolvePending(name)
+		mc.l.Unlock()
+		return
+	}
+	ctx := mc.ctx
+	if ctx == nil || ctx.Err() != nil {
+		mc.l.Unlock()
+		return
+	}
+	if err := mc.validateCluster(fresh); err != nil {
+		mc.recordPending(fresh, &clusterBuildError{reason: ReasonValidationFailed, err: err})
+		mc.l.Unlock()
+		return
+	}
+	attempts := pending.Attempts
+	// rebuild skip the cluster while building, unless the configuration modified
+	pending.NextRetry = time.Now().Add(mc.retryDelay(attempts + 1))
+	snapshot := mc.snapshotHandlers()
+	beforeStartList := mc.beforeStartHandlers()
+	mc.l.Unlock()
+
+	cli, err := mc.buildAndStart(ctx, fresh, mc.Options, beforeStartList)
+
+	mc.l.Lock()
+	current, stillPending := mc.pendingClusterMap[name]
+	if err != nil {
+		if stillPending {
+			mc.recordPending(fresh, err)
+		}
+		mc.l.Unlock()
+		return
+	}
+	_, exist := mc.MingleClientMap[name]
+	if exist || !stillPending || !sameClusterCfgInfo(current.ClusterCfgInfo, fresh) || ctx.Err() != nil {
+		// rebuilt, removed or modified while building
+		mc.l.Unlock()
+		klog.InfoS("Pending cluster changed while retrying, discard the client", "clusterName", name)
+		mc.forgetClusterTypes(cli)
+		mc.shutdownCluster(cli)
+		return
+	}
+	if mc.MingleClientMap == nil {
+		mc.MingleClientMap = map[string]api.MingleClient{}
+	}
+	mc.MingleClientMap[name] = cli
+	mc.resolvePending(name)
+	// enqueue with lock, so the state events of the client delivered after OnAdd
+	mc.enqueueClusterEvent(ClusterEvent{Type: clusterAdded, Client: cli}, mc.clusterEventHandlerList)
+	lateList := mc.handlersSince(snapshot)
+	handlerList := make([]api.ClusterEventHandler, len(mc.clusterEventHandlerList))
+	copy(handlerList, mc.clusterEventHandlerList)
+	mc.l.Unlock()
+
+	// the handlers registered while building missed this cluster
+	for _, handler := range lateList {
+		if err := handler(ctx, cli); err != nil {
+			klog.ErrorS(err, "Invoke handler registered while retrying failed", "clusterName", name)
+		}
+	}
+	mc.notifyClusterAdded(ctx, handlerList, cli)
+	klog.InfoS("Retry add mingle client successful!", "clusterName", name, "attempts", attempts+1)
+}
+
+func sameClusterCfgInfo(a, b api.ClusterCfgInfo) bool {
+	return a.GetKubeConfigType() == b.GetKubeConfigType() &&
+		a.GetKubeConfig() == b.GetKubeConfig() &&
+		a.GetKubeContext() == b.GetKubeContext() &&
+		labels.Equals(configuration.GetClusterLabels(a), configuration.GetClusterLabels(b))
+}
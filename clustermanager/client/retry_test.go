@@ -0,0 +1,290 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/configuration"
+)
+
+func TestRetryDelay(t *testing.T) {
+	mc := &multiClient{
+		CompletedConfig: &CompletedConfig{
+			&completeConfig{
+				MultiClientConfig: &MultiClientConfig{
+					RetryBaseDelay: time.Second,
+					RetryMaxDelay:  time.Second * 10,
+				},
+			},
+		},
+	}
+
+	expected := []time.Duration{time.Second, time.Second * 2, time.Second * 4, time.Second * 8, time.Second * 10, time.Second * 10}
+	for i, delay := range expected {
+		if got := mc.retryDelay(i + 1); got != delay {
+			t.Errorf("attempts %d retry delay should be %s, but got %s", i+1, delay, got)
+		}
+	}
+}
+
+func TestRetryPendingCluster(t *testing.T) {
+	oldInterval := defaultRetryCheckInterval
+	defaultRetryCheckInterval = time.Millisecond * 50
+	defer func() { defaultRetryCheckInterval = oldInterval }()
+
+	cfgManager := &configuration.FakeConfiguration{
+		GetAllFunc: func() ([]api.ClusterCfgInfo, error) {
+			return []api.ClusterCfgInfo{
+				configuration.NewFakeClusterCfgInfo("ok", api.KubeConfigTypeRawString, "", "ok"),
+				configuration.NewFakeClusterCfgInfo("flaky", api.KubeConfigTypeRawString, "", "flaky"),
+			}, nil
+		},
+	}
+
+	var failed int32
+	mcc := NewMultiClientConfig()
+	mcc.FetchInterval = 0
+	mcc.RetryBaseDelay = time.Millisecond * 100
+	mcc.RetryMaxDelay = time.Millisecond * 200
+	mcc.ClusterCfgManager = cfgManager
+	mcc.BuildClientFunc = func(info api.ClusterCfgInfo, opts *Options) (api.MingleClient, error) {
+		if info.GetName() == "flaky" && atomic.AddInt32(&failed, 1) <= 3 {
+			return nil, errors.New("connection refused")
+		}
+		return NewFackeClient(info, opts)
+	}
+	cc, err := Complete(mcc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc, err := cc.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	go mc.Start(ctx)
+	time.Sleep(time.Millisecond * 50)
+
+	if len(mc.GetAll()) != 1 {
+		t.Fatalf("only one cluster should be connected, but got %d", len(mc.GetAll()))
+	}
+	pendingList := mc.(PendingClusterStatus).GetPendingClusters()
+	if len(pendingList) != 1 || pendingList[0].Name != "flaky" {
+		t.Fatalf("cluster flaky should be pending, but got %+v", pendingList)
+	}
+	if pendingList[0].Reason != ReasonBuildFailed || pendingList[0].Attempts != 1 {
+		t.Errorf("pending reason should be %s with 1 attempt, but got %s with %d", ReasonBuildFailed, pendingList[0].Reason, pendingList[0].Attempts)
+	}
+
+	time.Sleep(time.Second * 1)
+	if len(mc.GetAll()) != 2 {
+		t.Fatalf("flaky cluster should be connected after retry, but got %d clusters", len(mc.GetAll()))
+	}
+	if pendingList = mc.(PendingClusterStatus).GetPendingClusters(); len(pendingList) != 0 {
+		t.Errorf("pending clusters should be empty, but got %+v", pendingList)
+	}
+	if atomic.LoadInt32(&failed) != 4 {
+		t.Errorf("flaky cluster should be built 4 times, but got %d", atomic.LoadInt32(&failed))
+	}
+}
+
+func TestRetryPendingWithoutLock(t *testing.T) {
+	var (
+		slowFailed int32
+		goneExist  int32 = 1
+	)
+	building := make(chan struct{})
+	release := make(chan struct{})
+
+	mcc := NewMultiClientConfig()
+	mcc.FetchInterval = 0
+	mcc.RetryBaseDelay = time.Hour
+	mcc.RetryMaxDelay = time.Hour
+	mcc.ClusterCfgManager = &configuration.FakeConfiguration{
+		GetAllFunc: func() ([]api.ClusterCfgInfo, error) {
+			list := []api.ClusterCfgInfo{
+				configuration.NewFakeClusterCfgInfo("ok", api.KubeConfigTypeRawString, "", "ok"),
+				configuration.NewFakeClusterCfgInfo("slow", api.KubeConfigTypeRawString, "", "slow"),
+			}
+			if atomic.LoadInt32(&goneExist) == 1 {
+				list = append(list, configuration.NewFakeClusterCfgInfo("gone", api.KubeConfigTypeRawString, "", "gone"))
+			}
+			return list, nil
+		},
+	}
+	mcc.BuildClientFunc = func(info api.ClusterCfgInfo, opts *Options) (api.MingleClient, error) {
+		switch info.GetName() {
+		case "gone":
+			return nil, errors.New("connection refused")
+		case "slow":
+			if atomic.AddInt32(&slowFailed, 1) == 1 {
+				return nil, errors.New("connection refused")
+			}
+			close(building)
+			<-release
+		}
+		return NewFackeClient(info, opts)
+	}
+	cc, err := Complete(mcc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli, err := cc.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc := cli.(*multiClient)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		mc.Start(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+	time.Sleep(time.Millisecond * 50)
+
+	if len(mc.GetPendingClusters()) != 2 {
+		t.Fatalf("expect slow and gone pending, but got %+v", mc.GetPendingClusters())
+	}
+	atomic.StoreInt32(&goneExist, 0)
+	mc.l.Lock()
+	for _, pending := range mc.pendingClusterMap {
+		pending.NextRetry = time.Time{}
+	}
+	mc.l.Unlock()
+
+	retried := make(chan struct{})
+	go func() {
+		defer close(retried)
+		mc.retryPendingOnce()
+	}()
+	<-building
+
+	got := make(chan error)
+	go func() {
+		_, err := mc.GetWithName("ok")
+		got <- err
+	}()
+	select {
+	case err = <-got:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Error("GetWithName should not be blocked by retrying slow cluster")
+	}
+
+	close(release)
+	<-retried
+	if _, err = mc.GetWithName("slow"); err != nil {
+		t.Errorf("slow cluster should be connected after retry, but got %+v", err)
+	}
+	if pendingList := mc.GetPendingClusters(); len(pendingList) != 0 {
+		t.Errorf("gone cluster removed from source should not be pending, but got %+v", pendingList)
+	}
+}
+
+// shutdownRecordClient record Shutdown invoked
+type shutdownRecordClient struct {
+	*FakeClient
+	shutdown int32
+}
+
+func (c *shutdownRecordClient) Shutdown(timeout time.Duration) error {
+	atomic.AddInt32(&c.shutdown, 1)
+	c.Stop()
+	return nil
+}
+
+func (c *shutdownRecordClient) Done() <-chan struct{} {
+	return c.StopCh
+}
+
+func (c *shutdownRecordClient) Err() error {
+	return nil
+}
+
+func TestRetryPendingDiscard(t *testing.T) {
+	var failed int32
+	building := make(chan struct{})
+	release := make(chan struct{})
+	built := make(chan *shutdownRecordClient, 1)
+
+	mcc := NewMultiClientConfig()
+	mcc.FetchInterval = 0
+	mcc.RetryBaseDelay = time.Hour
+	mcc.RetryMaxDelay = time.Hour
+	mcc.ClusterCfgManager = &configuration.FakeConfiguration{
+		GetAllFunc: func() ([]api.ClusterCfgInfo, error) {
+			return []api.ClusterCfgInfo{
+				configuration.NewFakeClusterCfgInfo("slow", api.KubeConfigTypeRawString, "", "slow"),
+			}, nil
+		},
+	}
+	mcc.BuildClientFunc = func(info api.ClusterCfgInfo, opts *Options) (api.MingleClient, error) {
+		if atomic.AddInt32(&failed, 1) == 1 {
+			return nil, errors.New("connection refused")
+		}
+		close(building)
+		<-release
+		cli, _ := NewFackeClient(info, opts)
+		rc := &shutdownRecordClient{FakeClient: cli.(*FakeClient)}
+		built <- rc
+		return rc, nil
+	}
+	cc, err := Complete(mcc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli, err := cc.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc := cli.(*multiClient)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		mc.Start(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+	time.Sleep(time.Millisecond * 50)
+
+	mc.l.Lock()
+	mc.pendingClusterMap["slow"].NextRetry = time.Time{}
+	mc.l.Unlock()
+
+	retried := make(chan struct{})
+	go func() {
+		defer close(retried)
+		mc.retryPendingOnce()
+	}()
+	<-building
+	// resolved by others while building, the built client is discarded
+	mc.l.Lock()
+	mc.resolvePending("slow")
+	mc.l.Unlock()
+	close(release)
+	<-retried
+
+	if rc := <-built; atomic.LoadInt32(&rc.shutdown) != 1 {
+		t.Error("discarded client should be shutdown gracefully")
+	}
+	if _, err = mc.GetWithName("slow"); err == nil {
+		t.Error("discarded client should not be added")
+	}
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/configuration"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SelectorClient target clusters with label selector, labels come from configuration.ClusterMetadata.
+// nil selector matches all clusters, the cluster rebuilt when its labels modified.
+type SelectorClient interface {
+	// GetAllWithSelector returns all MingleClient which labels match selector
+	GetAllWithSelector(selector labels.Selector) []api.MingleClient
+
+	// AddResourceEventHandlerWithSelector AddResourceEventHandler only with the matched clusters
+	AddResourceEventHandlerWithSelector(selector labels.Selector, obj rtclient.Object, handler cache.ResourceEventHandler) error
+
+	// WatchWithSelector Watch only with the matched clusters
+	WatchWithSelector(selector labels.Selector, obj rtclient.Object, queue api.WorkQueue, evtHandler api.EventHandler, predicates ...api.Predicate) error
+
+	// AddClusterEventHandlerWithSelector AddClusterEventHandler only notify the matched clusters
+	AddClusterEventHandlerWithSelector(selector labels.Selector, handler api.ClusterEventHandler)
+}
+
+// GetAllWithSelector implements SelectorClient
+func (mc *multiClient) GetAllWithSelector(selector labels.Selector) []api.MingleClient {
+	mc.l.Lock()
+	defer mc.l.Unlock()
+
+	list := make([]api.MingleClient, 0, len(mc.MingleClientMap))
+	for _, cli := range mc.MingleClientMap {
+		if configuration.MatchSelector(cli.GetClusterCfgInfo(), selector) {
+			list = append(list, cli)
+		}
+	}
+	return list
+}
+
+// AddClusterEventHandlerWithSelector implements SelectorClient
+func (mc *multiClient) AddClusterEventHandlerWithSelector(selector labels.Selector, handler api.ClusterEventHandler) {
+	h := &selectorClusterEventHandler{selector: selector, handler: handler}
+	if lh, ok := handler.(ClusterLifecycleHandler); ok {
+		// keep lifecycle handler delivered in order with lifecycle events
+		mc.AddClusterEventHandler(&selectorClusterLifecycleHandler{selectorClusterEventHandler: h, lifecycle: lh})
+		return
+	}
+	mc.AddClusterEventHandler(h)
+}
+
+// selectorClusterEventHandler skip the clusters not match selector
+type selectorClusterEventHandler struct {
+	selector labels.Selector
+	handler  api.ClusterEventHandler
+}
+
+func (h *selectorClusterEventHandler) OnAdd(ctx context.Context, cli api.MingleClient) {
+	if configuration.MatchSelector(cli.GetClusterCfgInfo(), h.selector) {
+		h.handler.OnAdd(ctx, cli)
+	}
+}
+
+func (h *selectorClusterEventHandler) OnDelete(ctx context.Context, cli api.MingleClient) {
+	if configuration.MatchSelector(cli.GetClusterCfgInfo(), h.selector) {
+		h.handler.OnDelete(ctx, cli)
+	}
+}
+
+// selectorClusterLifecycleHandler skip the lifecycle events of clusters not match selector,
+// ClusterConfigUpdated is converted to OnAdd or OnDelete when the modified labels change the matched result
+type selectorClusterLifecycleHandler struct {
+	*selectorClusterEventHandler
+	lifecycle ClusterLifecycleHandler
+}
+
+func (h *selectorClusterLifecycleHandler) OnClusterEvent(ctx context.Context, event ClusterEvent) {
+	matched := configuration.MatchSelector(event.Client.GetClusterCfgInfo(), h.selector)
+	if event.Type != ClusterConfigUpdated || event.OldClient == nil {
+		if matched {
+			h.lifecycle.OnClusterEvent(ctx, event)
+		}
+		return
+	}
+
+	oldMatched := configuration.MatchSelector(event.OldClusterCfgInfo(), h.selector)
+	switch {
+	case oldMatched && matched:
+		h.lifecycle.OnClusterEvent(ctx, event)
+	case oldMatched:
+		h.handler.OnDelete(ctx, event.OldClient)
+	case matched:
+		h.handler.OnAdd(ctx, event.Client)
+	}
+}
@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/configuration"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type recordClusterEventHandler struct {
+	added []api.MingleClient
+}
+
+func (r *recordClusterEventHandler) OnAdd(ctx context.Context, cli api.MingleClient) {
+	r.added = append(r.added, cli)
+}
+
+func (r *recordClusterEventHandler) OnDelete(ctx context.Context, cli api.MingleClient) {}
+
+func TestSelectorClient(t *testing.T) {
+	added := map[string]bool{}
+	buildFakeClient := func(name string, clusterLabels map[string]string) *FakeClient {
+		return &FakeClient{
+			ClusterCfg: configuration.BuildClusterCfgInfoWithMetadata(name, api.KubeConfigTypeRawString, "", "", clusterLabels, nil),
+			AddResourceEventHandlerFunc: func(obj rtclient.Object, handler cache.ResourceEventHandler) error {
+				added[name] = true
+				return nil
+			},
+		}
+	}
+
+	mc := &multiClient{
+		MingleClientMap: map[string]api.MingleClient{
+			"cluster-1": buildFakeClient("cluster-1", map[string]string{"env": "prod", "region": "eu"}),
+			"cluster-2": buildFakeClient("cluster-2", map[string]string{"env": "prod", "region": "us"}),
+			"cluster-3": buildFakeClient("cluster-3", nil),
+		},
+	}
+	selector, err := labels.Parse("env=prod,region=eu")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if list := mc.GetAllWithSelector(selector); len(list) != 1 || list[0].GetClusterCfgInfo().GetName() != "cluster-1" {
+		t.Errorf("expect cluster-1 match selector, but got %+v", list)
+	}
+	if list := mc.GetAllWithSelector(nil); len(list) != 3 {
+		t.Errorf("nil selector should match all clusters, but got %d", len(list))
+	}
+
+	t.Run("resource event handler", func(t *testing.T) {
+		if err := mc.AddResourceEventHandlerWithSelector(selector, nil, &cache.ResourceEventHandlerFuncs{}); err != nil {
+			t.Fatal(err)
+		}
+		if len(added) != 1 || !added["cluster-1"] {
+			t.Errorf("expect handler only added to cluster-1, but got %+v", added)
+		}
+	})
+
+	t.Run("cluster event handler", func(t *testing.T) {
+		handler := &recordClusterEventHandler{}
+		mc.AddClusterEventHandlerWithSelector(labels.SelectorFromSet(labels.Set{"env": "prod"}), handler)
+
+		names := []string{}
+		for _, cli := range handler.added {
+			names = append(names, cli.GetClusterCfgInfo().GetName())
+		}
+		sort.Strings(names)
+		if len(names) != 2 || names[0] != "cluster-1" || names[1] != "cluster-2" {
+			t.Errorf("expect cluster-1 and cluster-2 notified, but got %v", names)
+		}
+	})
+
+	t.Run("cluster lifecycle handler", func(t *testing.T) {
+		handler := &recordLifecycleHandler{}
+		mc.AddClusterEventHandlerWithSelector(selector, handler)
+
+		relabeled := func(name string, clusterLabels map[string]string) ClusterEvent {
+			return ClusterEvent{
+				Type:      ClusterConfigUpdated,
+				Client:    buildFakeClient(name, clusterLabels),
+				OldClient: mc.MingleClientMap[name],
+			}
+		}
+		for _, event := range []ClusterEvent{
+			{Type: ClusterConnected, Client: mc.MingleClientMap["cluster-1"]},
+			{Type: ClusterConnected, Client: mc.MingleClientMap["cluster-2"]},
+			relabeled("cluster-1", map[string]string{"env": "prod", "region": "us"}),
+			relabeled("cluster-2", map[string]string{"env": "prod", "region": "eu"}),
+		} {
+			mc.l.Lock()
+			done := mc.enqueueClusterEvent(event, mc.clusterEventHandlerList)
+			mc.l.Unlock()
+			<-done
+		}
+
+		expected := []string{"Add", "Connected", "Delete", "Add"}
+		events := handler.getEvents()
+		if len(events) != len(expected) {
+			t.Fatalf("expect events %v, but got %v", expected, events)
+		}
+		for i := range expected {
+			if events[i] != expected[i] {
+				t.Fatalf("expect events %v, but got %v", expected, events)
+			}
+		}
+	})
+
+	t.Run("fan-out", func(t *testing.T) {
+		result := mc.FanOut(context.TODO(), &FanOutOptions{Selector: selector}, func(ctx context.Context, cli api.MingleClient) (interface{}, error) {
+			return cli.GetClusterCfgInfo().GetName(), nil
+		})
+		if len(result.Results) != 1 || result.Results["cluster-1"] != "cluster-1" || len(result.Errors) != 0 {
+			t.Errorf("expect fan-out only cluster-1, but got %+v", result)
+		}
+	})
+
+	t.Run("labels modified", func(t *testing.T) {
+		old := mc.MingleClientMap["cluster-1"].GetClusterCfgInfo()
+		fresh := configuration.BuildClusterCfgInfoWithMetadata("cluster-1", api.KubeConfigTypeRawString, "", "", map[string]string{"env": "test"}, nil)
+		if sameClusterCfgInfo(old, fresh) {
+			t.Error("labels modified should rebuild cluster")
+		}
+		fresh = configuration.BuildClusterCfgInfoWithMetadata("cluster-1", api.KubeConfigTypeRawString, "", "", map[string]string{"env": "prod", "region": "eu"}, map[string]string{"k": "v"})
+		if !sameClusterCfgInfo(old, fresh) {
+			t.Error("annotations modified should not rebuild cluster")
+		}
+	})
+}
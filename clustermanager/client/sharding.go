@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"errors"
+
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/sharding"
+	"k8s.io/klog/v2"
+)
+
+// ShardingStatus reports the sharding status of multiclient
+type ShardingStatus interface {
+	// ShardMembers returns all alive replicas, returns nil when sharding not enabled
+	ShardMembers() []string
+
+	// IsShardOwner returns true if the cluster belongs to this replica,
+	// always true when sharding not enabled
+	IsShardOwner(clusterName string) bool
+}
+
+func (mc *multiClient) shardingEnabled() bool {
+	return mc.Sharding != nil && mc.Sharding.Enabled
+}
+
+func (cc *CompletedConfig) completeSharding() error {
+	if cc.Sharding == nil || !cc.Sharding.Enabled {
+		return nil
+	}
+	if cc.MultiClusterLeaderElection != nil && cc.MultiClusterLeaderElection.Enabled {
+		return errors.New("multiclient leader election and sharding can't enable at the same time")
+	}
+	if err := cc.Sharding.Complete(); err != nil {
+		return err
+	}
+
+	_, err := cc.managerKubeInterface()
+	return err
+}
+
+// runWithSharding register this replica and start clusters which belong to it,
+// blocks until the context is cancelled.
+func (mc *multiClient) runWithSharding(ctx context.Context) error {
+	if mc.ManagerKubeInterface == nil {
+		return errors.New("sharding manager cluster kubernetes interface is nil")
+	}
+
+	membership, err := sharding.NewMembership(mc.ManagerKubeInterface, mc.Sharding, func(members []string) {
+		// ownership moved, rebuild clusters immediately
+		go func() {
+			if err := mc.FetchClientInfoOnce(); err != nil {
+				klog.ErrorS(err, "FetchClientInfoOnce with sharding members changed failed")
+			}
+		}()
+	})
+	if err != nil {
+		return err
+	}
+	if err = membership.Sync(ctx); err != nil {
+		return err
+	}
+
+	mc.l.Lock()
+	mc.membership = membership
+	mc.l.Unlock()
+
+	go membership.Start(ctx)
+
+	return mc.run(ctx)
+}
+
+// filterShardOwned returns the clusters belong to this replica
+func (mc *multiClient) filterShardOwned(list []api.ClusterCfgInfo) []api.ClusterCfgInfo {
+	if mc.membership == nil {
+		return list
+	}
+
+	result := make([]api.ClusterCfgInfo, 0, len(list))
+	for _, info := range list {
+		if mc.membership.IsOwner(info.GetName()) {
+			result = append(result, info)
+		}
+	}
+	return result
+}
+
+// removeNotShardOwned remove the running and pending clusters not belong to this replica,
+// returns the clusters should be stopped, must hold lock
+func (mc *multiClient) removeNotShardOwned() []api.MingleClient {
+	if mc.membership == nil {
+		return nil
+	}
+
+	stopList := []api.MingleClient{}
+	for name, cli := range mc.MingleClientMap {
+		if !mc.membership.IsOwner(name) {
+			stopList = append(stopList, cli)
+			delete(mc.MingleClientMap, name)
+		}
+	}
+	for name := range mc.pendingClusterMap {
+		if !mc.membership.IsOwner(name) {
+			mc.resolvePending(name)
+		}
+	}
+	return stopList
+}
+
+// ShardMembers implements ShardingStatus
+func (mc *multiClient) ShardMembers() []string {
+	mc.l.Lock()
+	defer mc.l.Unlock()
+
+	if mc.membership == nil {
+		return nil
+	}
+	return mc.membership.Members()
+}
+
+// IsShardOwner implements ShardingStatus
+func (mc *multiClient) IsShardOwner(clusterName string) bool {
+	mc.l.Lock()
+	defer mc.l.Unlock()
+
+	if mc.membership == nil {
+		return true
+	}
+	return mc.membership.IsOwner(clusterName)
+}
@@ -0,0 +1,191 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/configuration"
+	"github.com/symcn/pkg/clustermanager/sharding"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestMultiClientSharding(t *testing.T) {
+	num := 20
+	kubeInterface := fake.NewSimpleClientset()
+	cfgManager := &configuration.FakeConfiguration{
+		GetAllFunc: func() ([]api.ClusterCfgInfo, error) {
+			list := make([]api.ClusterCfgInfo, 0, num)
+			for i := 0; i < num; i++ {
+				list = append(list, configuration.NewFakeClusterCfgInfo(fmt.Sprintf("%d", i), api.KubeConfigTypeRawString, "", fmt.Sprintf("cluster-%d", i)))
+			}
+			return list, nil
+		},
+	}
+
+	var added, deleted int32
+	buildMultiClient := func(identity string) api.MultiMingleClient {
+		cfg := sharding.NewConfig()
+		cfg.Identity = identity
+		cfg.LeaseDuration = time.Second * 2
+		cfg.RenewInterval = time.Millisecond * 200
+
+		mcc := NewMultiClientConfig()
+		mcc.FetchInterval = 0
+		mcc.ClusterCfgManager = cfgManager
+		mcc.BuildClientFunc = NewFackeClient
+		mcc.ManagerKubeInterface = kubeInterface
+		mcc.Sharding = cfg
+		cc, err := Complete(mcc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mc, err := cc.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return mc
+	}
+
+	mc1 := buildMultiClient("replica-1")
+	mc1.AddClusterEventHandler(&mockClusterEventHandler{
+		onAdd:    func() { atomic.AddInt32(&added, 1) },
+		onDelete: func() { atomic.AddInt32(&deleted, 1) },
+	})
+	mc2 := buildMultiClient("replica-2")
+
+	ctx1, cancel1 := context.WithCancel(context.TODO())
+	defer cancel1()
+	ctx2, cancel2 := context.WithCancel(context.TODO())
+	defer cancel2()
+
+	go mc1.Start(ctx1)
+	time.Sleep(time.Millisecond * 300)
+	if len(mc1.GetAll()) != num {
+		t.Fatalf("single replica should own all %d clusters, but got %d", num, len(mc1.GetAll()))
+	}
+
+	go mc2.Start(ctx2)
+	time.Sleep(time.Second * 1)
+
+	total := len(mc1.GetAll()) + len(mc2.GetAll())
+	if total != num || len(mc1.GetAll()) == 0 || len(mc2.GetAll()) == 0 {
+		t.Fatalf("clusters should be sharded across replicas, but got %d and %d", len(mc1.GetAll()), len(mc2.GetAll()))
+	}
+	for _, cli := range mc1.GetAll() {
+		if _, err := mc2.GetWithName(cli.GetClusterCfgInfo().GetName()); err == nil {
+			t.Errorf("cluster %s should not belong to both replicas", cli.GetClusterCfgInfo().GetName())
+		}
+	}
+	if int(atomic.LoadInt32(&deleted)) != num-len(mc1.GetAll()) {
+		t.Errorf("moved clusters should fire OnDelete, expect %d but got %d", num-len(mc1.GetAll()), deleted)
+	}
+
+	// replica-2 leave, replica-1 take over
+	cancel2()
+	time.Sleep(time.Second * 1)
+	if len(mc1.GetAll()) != num {
+		t.Errorf("replica-1 should take over all %d clusters, but got %d", num, len(mc1.GetAll()))
+	}
+	if atomic.LoadInt32(&added) != int32(num)+atomic.LoadInt32(&deleted) {
+		t.Errorf("take over clusters should fire OnAdd, expect %d but got %d", int32(num)+deleted, added)
+	}
+}
+
+func TestShardLeaseExpired(t *testing.T) {
+	// the manager cluster unavailable, lease and cluster configuration both failed
+	var unavailable int32
+	kubeInterface := fake.NewSimpleClientset()
+	kubeInterface.PrependReactor("*", "leases", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if atomic.LoadInt32(&unavailable) == 1 {
+			return true, nil, errors.New("apiserver unavailable")
+		}
+		return false, nil, nil
+	})
+	cfgManager := &configuration.FakeConfiguration{
+		GetAllFunc: func() ([]api.ClusterCfgInfo, error) {
+			if atomic.LoadInt32(&unavailable) == 1 {
+				return nil, errors.New("apiserver unavailable")
+			}
+			return []api.ClusterCfgInfo{
+				configuration.NewFakeClusterCfgInfo("", api.KubeConfigTypeRawString, "", "cluster-1"),
+				configuration.NewFakeClusterCfgInfo("", api.KubeConfigTypeRawString, "", "cluster-2"),
+			}, nil
+		},
+	}
+
+	cfg := sharding.NewConfig()
+	cfg.Identity = "replica-1"
+	cfg.LeaseDuration = time.Second
+	cfg.RenewInterval = time.Millisecond * 100
+	mcc := NewMultiClientConfig()
+	mcc.FetchInterval = 0
+	mcc.ClusterCfgManager = cfgManager
+	mcc.BuildClientFunc = NewFackeClient
+	mcc.ManagerKubeInterface = kubeInterface
+	mcc.Sharding = cfg
+	cc, err := Complete(mcc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc, err := cc.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	stopped := make(chan struct{})
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+	go func() {
+		defer close(stopped)
+		mc.Start(ctx)
+	}()
+
+	waitClusters := func(expect int) {
+		err := wait.PollImmediate(time.Millisecond*50, time.Second*5, func() (bool, error) {
+			return len(mc.GetAll()) == expect, nil
+		})
+		if err != nil {
+			t.Fatalf("expect %d clusters, but got %d", expect, len(mc.GetAll()))
+		}
+	}
+	waitClusters(2)
+
+	// lease not renewed, give up all clusters before the others take over
+	atomic.StoreInt32(&unavailable, 1)
+	waitClusters(0)
+	if mc.(ShardingStatus).IsShardOwner("cluster-1") {
+		t.Error("expired replica should not own any cluster")
+	}
+
+	// renewed, take over again
+	atomic.StoreInt32(&unavailable, 0)
+	waitClusters(2)
+}
+
+type mockClusterEventHandler struct {
+	onAdd    func()
+	onDelete func()
+}
+
+func (m *mockClusterEventHandler) OnAdd(ctx context.Context, cli api.MingleClient) {
+	if m.onAdd != nil {
+		m.onAdd()
+	}
+}
+
+func (m *mockClusterEventHandler) OnDelete(ctx context.Context, cli api.MingleClient) {
+	if m.onDelete != nil {
+		m.onDelete()
+	}
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"sync"
+
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/metrics"
+	"k8s.io/klog/v2"
+)
+
+var (
+	metricTypePre    = "multiclient_"
+	clusterLabelName = "cluster"
+	reasonLabelName  = "reason"
+	multiClientStats *stats
+	statsOnce        sync.Once
+)
+
+// metrics key with labels
+const (
+	PendingClusterCount   = "pending_clusters"
+	PendingClusterReason  = "pending_cluster"
+	ClusterBuildFailTotal = "cluster_build_fail_total"
+)
+
+type stats struct {
+	metric api.Metrics
+}
+
+func getStats() *stats {
+	statsOnce.Do(func() {
+		metric, err := metrics.NewMetrics(metricTypePre, nil)
+		if err != nil {
+			klog.ErrorS(err, "build multiclient metrics failed")
+			return
+		}
+		multiClientStats = &stats{metric: metric}
+	})
+	return multiClientStats
+}
+
+// setPending record cluster pending with reason
+func (s *stats) setPending(clusterName, reason string, total int) {
+	if s == nil {
+		return
+	}
+	s.metric.Gauge(PendingClusterCount).Set(float64(total))
+	s.metric.CounterWithLabels(ClusterBuildFailTotal, map[string]string{clusterLabelName: clusterName}).Inc()
+	s.metric.GaugeWithLabels(PendingClusterReason, map[string]string{clusterLabelName: clusterName, reasonLabelName: reason}).Set(1)
+}
+
+// deletePending remove cluster pending record
+func (s *stats) deletePending(clusterName, reason string, total int) {
+	if s == nil {
+		return
+	}
+	s.metric.Gauge(PendingClusterCount).Set(float64(total))
+	s.metric.DeleteWithLabels(PendingClusterReason, map[string]string{clusterLabelName: clusterName, reasonLabelName: reason})
+}
+
+// resolvePending remove cluster pending record and the build failed counter of cluster
+func (s *stats) resolvePending(clusterName, reason string, total int) {
+	if s == nil {
+		return
+	}
+	s.deletePending(clusterName, reason, total)
+	s.metric.DeleteWithLabels(ClusterBuildFailTotal, map[string]string{clusterLabelName: clusterName})
+}
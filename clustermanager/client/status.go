@@ -0,0 +1,186 @@
+package client
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/configuration"
+	"k8s.io/klog/v2"
+)
+
+var (
+	defaultReportClusterStatusTimeout = time.Second * 5
+	// defaultClusterStatusRefreshChecks rewrite the unchanged status after the health check times,
+	// keep LastHealthCheck fresh without writing on each check
+	defaultClusterStatusRefreshChecks = 10
+)
+
+// serverVersion cached apiserver version of the client
+type serverVersion struct {
+	cli     api.MingleClient
+	version string
+}
+
+// reportedStatus the status written to the configuration source
+type reportedStatus struct {
+	info   api.ClusterCfgInfo
+	status configuration.ClusterStatus
+}
+
+// statusCache cached state of the reported clusters, just used by one goroutine
+type statusCache struct {
+	// versions apiserver version with the client
+	versions map[string]serverVersion
+	// reported the last status written to the configuration source
+	reported map[string]reportedStatus
+}
+
+func newStatusCache() *statusCache {
+	return &statusCache{
+		versions: map[string]serverVersion{},
+		reported: map[string]reportedStatus{},
+	}
+}
+
+// loopReportClusterStatus write the observed state of clusters to the configuration source until the context is cancelled
+func (mc *multiClient) loopReportClusterStatus(ctx context.Context, reporter configuration.ClusterStatusReporter, interval time.Duration) {
+	timer := time.NewTicker(interval)
+	defer timer.Stop()
+
+	cache := newStatusCache()
+	for {
+		select {
+		case <-timer.C:
+			mc.reportClusterStatusOnce(ctx, reporter, cache)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reportClusterStatusOnce report the running and pending clusters
+func (mc *multiClient) reportClusterStatusOnce(ctx context.Context, reporter configuration.ClusterStatusReporter, cache *statusCache) {
+	mc.l.Lock()
+	clients := make([]api.MingleClient, 0, len(mc.MingleClientMap))
+	for _, cli := range mc.MingleClientMap {
+		clients = append(clients, cli)
+	}
+	pendingList := make([]PendingCluster, 0, len(mc.pendingClusterMap))
+	for _, pending := range mc.pendingClusterMap {
+		pendingList = append(pendingList, *pending)
+	}
+	mc.l.Unlock()
+
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].GetClusterCfgInfo().GetName() < clients[j].GetClusterCfgInfo().GetName()
+	})
+	sort.Slice(pendingList, func(i, j int) bool {
+		return pendingList[i].Name < pendingList[j].Name
+	})
+
+	running := make(map[string]struct{}, len(clients))
+	for _, cli := range clients {
+		name := cli.GetClusterCfgInfo().GetName()
+		running[name] = struct{}{}
+
+		status := configuration.ClusterStatus{
+			Connected: cli.IsConnected(),
+		}
+		if hc, ok := cli.(HealthChecker); ok {
+			checkTime, err := hc.LastHealthCheck()
+			status.LastHealthCheck = checkTime
+			if err != nil {
+				status.LastError = err.Error()
+			}
+		}
+		if status.Connected {
+			status.Synced = cli.HasSynced()
+			status.ServerVersion = mc.getServerVersion(cli, cache.versions)
+		}
+		mc.reportClusterStatus(ctx, reporter, cli.GetClusterCfgInfo(), status, cache)
+	}
+	for name := range cache.versions {
+		if _, ok := running[name]; !ok {
+			delete(cache.versions, name)
+		}
+	}
+
+	for _, pending := range pendingList {
+		running[pending.Name] = struct{}{}
+		mc.reportClusterStatus(ctx, reporter, pending.ClusterCfgInfo, configuration.ClusterStatus{
+			LastHealthCheck: pending.LastAttempt,
+			LastError:       pending.Message,
+		}, cache)
+	}
+
+	// the clusters left write disconnected once, ignore the source object deleted
+	for name, reported := range cache.reported {
+		if _, ok := running[name]; ok {
+			continue
+		}
+		delete(cache.reported, name)
+		if ctx.Err() != nil || !mc.IsShardOwner(name) {
+			// stopped or moved to other replica, which reports the cluster
+			continue
+		}
+		if err := writeClusterStatus(ctx, reporter, reported.info, configuration.ClusterStatus{}); err != nil {
+			klog.ErrorS(err, "Report removed cluster status failed", "clusterName", name)
+		}
+	}
+}
+
+// getServerVersion returns cached version, request apiserver when the client changed
+func (mc *multiClient) getServerVersion(cli api.MingleClient, versions map[string]serverVersion) string {
+	name := cli.GetClusterCfgInfo().GetName()
+	if cached, ok := versions[name]; ok && cached.cli == cli {
+		return cached.version
+	}
+
+	kubeInterface := cli.GetKubeInterface()
+	if kubeInterface == nil {
+		return ""
+	}
+	info, err := kubeInterface.Discovery().ServerVersion()
+	if err != nil {
+		klog.ErrorS(err, "Get cluster server version failed", "clusterName", name)
+		return ""
+	}
+	versions[name] = serverVersion{cli: cli, version: info.GitVersion}
+	return info.GitVersion
+}
+
+// reportClusterStatus write status when it changed, the time of health check is refreshed with statusRefreshInterval
+func (mc *multiClient) reportClusterStatus(ctx context.Context, reporter configuration.ClusterStatusReporter, info api.ClusterCfgInfo, status configuration.ClusterStatus, cache *statusCache) {
+	if last, ok := cache.reported[info.GetName()]; ok && sameClusterStatus(last.status, status) &&
+		status.LastHealthCheck.Sub(last.status.LastHealthCheck) < mc.statusRefreshInterval() {
+		return
+	}
+	if err := writeClusterStatus(ctx, reporter, info, status); err != nil {
+		klog.ErrorS(err, "Report cluster status failed", "clusterName", info.GetName())
+		return
+	}
+	cache.reported[info.GetName()] = reportedStatus{info: info, status: status}
+}
+
+// statusRefreshInterval max age of the reported LastHealthCheck
+func (mc *multiClient) statusRefreshInterval() time.Duration {
+	interval := defaultHealthCheckInterval
+	if mc.Options != nil && mc.HealthCheckInterval > 0 {
+		interval = mc.HealthCheckInterval
+	}
+	return interval * time.Duration(defaultClusterStatusRefreshChecks)
+}
+
+func writeClusterStatus(ctx context.Context, reporter configuration.ClusterStatusReporter, info api.ClusterCfgInfo, status configuration.ClusterStatus) error {
+	reportCtx, cancel := context.WithTimeout(ctx, defaultReportClusterStatusTimeout)
+	defer cancel()
+
+	return reporter.ReportClusterStatus(reportCtx, info, status)
+}
+
+func sameClusterStatus(a, b configuration.ClusterStatus) bool {
+	a.LastHealthCheck, b.LastHealthCheck = time.Time{}, time.Time{}
+	return a == b
+}
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/configuration"
+	"k8s.io/client-go/kubernetes"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+// recordStatusReporter record the last status of clusters
+type recordStatusReporter struct {
+	configuration.FakeConfiguration
+	l        sync.Mutex
+	statuses map[string]configuration.ClusterStatus
+	reports  map[string]int
+}
+
+func (r *recordStatusReporter) ReportClusterStatus(ctx context.Context, clusterInfo api.ClusterCfgInfo, status configuration.ClusterStatus) error {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	r.statuses[clusterInfo.GetName()] = status
+	r.reports[clusterInfo.GetName()]++
+	return nil
+}
+
+func (r *recordStatusReporter) getStatus(name string) (configuration.ClusterStatus, bool) {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	status, ok := r.statuses[name]
+	return status, ok
+}
+
+func (r *recordStatusReporter) getReports(name string) int {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	return r.reports[name]
+}
+
+func TestReportClusterStatus(t *testing.T) {
+	reporter := &recordStatusReporter{statuses: map[string]configuration.ClusterStatus{}, reports: map[string]int{}}
+	var removed int32
+	reporter.GetAllFunc = func() ([]api.ClusterCfgInfo, error) {
+		if atomic.LoadInt32(&removed) == 1 {
+			return nil, nil
+		}
+		return []api.ClusterCfgInfo{
+			configuration.NewFakeClusterCfgInfo("", api.KubeConfigTypeRawString, "", "cluster-1"),
+			configuration.NewFakeClusterCfgInfo("", api.KubeConfigTypeRawString, "", "cluster-2"),
+		}, nil
+	}
+
+	var unhealthy int32
+	mcc := NewMultiClientConfig()
+	mcc.FetchInterval = 0
+	mcc.ClusterStatusReportInterval = time.Millisecond * 10
+	mcc.ClusterCfgManager = reporter
+	mcc.BuildClientFunc = func(info api.ClusterCfgInfo, opts *Options) (api.MingleClient, error) {
+		if info.GetName() == "cluster-2" {
+			return nil, errors.New("build failed")
+		}
+		cli, _ := NewFackeClient(info, opts)
+		cli.(*FakeClient).GetKubeInterfaceFunc = func() kubernetes.Interface {
+			return kubefake.NewSimpleClientset()
+		}
+		cli.(*FakeClient).IsConnectedFunc = func() bool {
+			return atomic.LoadInt32(&unhealthy) == 0
+		}
+		cli.(*FakeClient).LastHealthCheckFunc = func() (time.Time, error) {
+			if atomic.LoadInt32(&unhealthy) == 1 {
+				return time.Now(), errors.New("connection refused")
+			}
+			return time.Now(), nil
+		}
+		return cli, nil
+	}
+	cc, err := Complete(mcc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli, err := cc.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		cli.Start(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	time.Sleep(time.Millisecond * 100)
+	status, ok := reporter.getStatus("cluster-1")
+	if !ok || !status.Connected || !status.Synced || status.LastHealthCheck.IsZero() || status.LastError != "" {
+		t.Errorf("expect cluster-1 connected, but got %+v", status)
+	}
+	if reports := reporter.getReports("cluster-1"); reports != 1 {
+		t.Errorf("expect cluster-1 reported once when only health check time changed, but got %d", reports)
+	}
+	status, ok = reporter.getStatus("cluster-2")
+	if !ok || status.Connected || status.LastError == "" {
+		t.Errorf("expect cluster-2 pending with error, but got %+v", status)
+	}
+
+	// health check failed, the real error reported
+	atomic.StoreInt32(&unhealthy, 1)
+	time.Sleep(time.Millisecond * 100)
+	status, ok = reporter.getStatus("cluster-1")
+	if !ok || status.Connected || status.LastError != "connection refused" {
+		t.Errorf("expect cluster-1 disconnected with health check error, but got %+v", status)
+	}
+
+	// removed cluster write disconnected once
+	atomic.StoreInt32(&unhealthy, 0)
+	time.Sleep(time.Millisecond * 100)
+	reports := reporter.getReports("cluster-1")
+	atomic.StoreInt32(&removed, 1)
+	if err = cli.(*multiClient).FetchClientInfoOnce(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 100)
+	status, _ = reporter.getStatus("cluster-1")
+	if status.Connected || status.Synced || status.LastError != "" || reporter.getReports("cluster-1") != reports+1 {
+		t.Errorf("expect removed cluster-1 reported disconnected once, but got %+v with %d reports", status, reporter.getReports("cluster-1")-reports)
+	}
+}
+
+func TestReportClusterStatusRefresh(t *testing.T) {
+	reporter := &recordStatusReporter{statuses: map[string]configuration.ClusterStatus{}, reports: map[string]int{}}
+	mc := &multiClient{
+		CompletedConfig: &CompletedConfig{
+			&completeConfig{
+				MultiClientConfig: &MultiClientConfig{
+					Options: &Options{HealthCheckInterval: time.Second},
+				},
+			},
+		},
+	}
+	info := configuration.NewFakeClusterCfgInfo("", api.KubeConfigTypeRawString, "", "cluster-1")
+	cache := newStatusCache()
+
+	now := time.Now()
+	for _, checkTime := range []time.Time{now, now.Add(time.Second), now.Add(time.Second * 9)} {
+		mc.reportClusterStatus(context.TODO(), reporter, info, configuration.ClusterStatus{Connected: true, LastHealthCheck: checkTime}, cache)
+	}
+	if reports := reporter.getReports("cluster-1"); reports != 1 {
+		t.Errorf("expect reported once when only health check time changed, but got %d", reports)
+	}
+
+	refreshTime := now.Add(time.Second * 10)
+	mc.reportClusterStatus(context.TODO(), reporter, info, configuration.ClusterStatus{Connected: true, LastHealthCheck: refreshTime}, cache)
+	status, _ := reporter.getStatus("cluster-1")
+	if reporter.getReports("cluster-1") != 2 || !status.LastHealthCheck.Equal(refreshTime) {
+		t.Errorf("expect health check time refreshed after %d checks, but got %+v", defaultClusterStatusRefreshChecks, status)
+	}
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"sort"
+
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/configuration"
+	"k8s.io/klog/v2"
+)
+
+// ClusterValidationStatus reports the validation results of clusters,
+// just recorded with MultiClientConfig.ValidateClusterCfg
+type ClusterValidationStatus interface {
+	// GetValidationResults returns the last result of all clusters sorted by name
+	GetValidationResults() []configuration.ValidationResult
+}
+
+// GetValidationResults implements ClusterValidationStatus
+func (mc *multiClient) GetValidationResults() []configuration.ValidationResult {
+	mc.l.Lock()
+	defer mc.l.Unlock()
+
+	list := make([]configuration.ValidationResult, 0, len(mc.validationResults))
+	for _, result := range mc.validationResults {
+		list = append(list, result)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// validateCluster validate and record the result before build client, must hold lock
+func (mc *multiClient) validateCluster(info api.ClusterCfgInfo) error {
+	if !mc.ValidateClusterCfg {
+		return nil
+	}
+
+	result := configuration.ValidateClusterCfgInfo(info)
+	if mc.validationResults == nil {
+		mc.validationResults = map[string]configuration.ValidationResult{}
+	}
+	mc.validationResults[info.GetName()] = result
+
+	for _, d := range result.Diagnostics {
+		if d.Severity == configuration.DiagnosticWarning {
+			klog.InfoS("Cluster configuration warning", "clusterName", info.GetName(), "code", d.Code, "message", d.Message)
+		}
+	}
+	return result.Err()
+}
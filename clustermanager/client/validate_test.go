@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/configuration"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestValidateClusterCfg(t *testing.T) {
+	cfg := clientcmdapi.NewConfig()
+	cfg.Clusters["cluster"] = &clientcmdapi.Cluster{Server: "https://cluster"}
+	cfg.AuthInfos["user"] = &clientcmdapi.AuthInfo{Token: "token"}
+	cfg.Contexts["ctx"] = &clientcmdapi.Context{Cluster: "cluster", AuthInfo: "user"}
+	data, err := clientcmd.Write(*cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mcc := NewMultiClientConfig()
+	mcc.FetchInterval = 0
+	mcc.ValidateClusterCfg = true
+	mcc.ClusterCfgManager = &configuration.FakeConfiguration{
+		GetAllFunc: func() ([]api.ClusterCfgInfo, error) {
+			return []api.ClusterCfgInfo{
+				configuration.BuildClusterCfgInfo("valid", api.KubeConfigTypeRawString, string(data), "ctx"),
+				configuration.BuildClusterCfgInfo("invalid", api.KubeConfigTypeRawString, string(data), "not-exist"),
+			}, nil
+		},
+	}
+	mcc.BuildClientFunc = NewFackeClient
+	cc, err := Complete(mcc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc, err := cc.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		mc.Start(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+	time.Sleep(time.Millisecond * 50)
+
+	if _, err = mc.GetWithName("valid"); err != nil {
+		t.Errorf("valid cluster should be connected, but got %+v", err)
+	}
+	pendingList := mc.(PendingClusterStatus).GetPendingClusters()
+	if len(pendingList) != 1 || pendingList[0].Name != "invalid" || pendingList[0].Reason != ReasonValidationFailed {
+		t.Errorf("invalid cluster should be pending with %s, but got %+v", ReasonValidationFailed, pendingList)
+	}
+
+	results := mc.(ClusterValidationStatus).GetValidationResults()
+	if len(results) != 2 || results[0].Name != "invalid" || results[0].Valid() || !results[1].Valid() {
+		t.Errorf("expect validation results recorded, but got %+v", results)
+	}
+}
@@ -0,0 +1,143 @@
+package configuration
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/symcn/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+)
+
+var (
+	listCAPIClusterTimeout = time.Second * 5
+
+	// CAPIClusterGVR Cluster API cluster resource
+	CAPIClusterGVR = schema.GroupVersionResource{Group: "cluster.x-k8s.io", Version: "v1beta1", Resource: "clusters"}
+
+	secretGVR = schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+)
+
+// CAPIKubeconfigSecretSuffix the kubeconfig secret of Cluster API cluster is <cluster>-kubeconfig
+// CAPIKubeconfigSecretKey the kubeconfig data key of the secret
+const (
+	CAPIKubeconfigSecretSuffix = "-kubeconfig"
+	CAPIKubeconfigSecretKey    = "value"
+)
+
+// cfgWithCAPI clusterconfiguration manager with Cluster API clusters and their kubeconfig secrets
+type cfgWithCAPI struct {
+	dynamicInterface dynamic.Interface
+	namespaces       []string
+	filter           FilterHandler
+}
+
+// NewClusterCfgManagerWithCAPI build cfgWithCAPI, discover clusters with all namespaces when namespaces is empty
+func NewClusterCfgManagerWithCAPI(dynamicInterface dynamic.Interface, namespaces []string) api.ClusterConfigurationManager {
+	return &cfgWithCAPI{
+		dynamicInterface: dynamicInterface,
+		namespaces:       namespaces,
+	}
+}
+
+// NewClusterCfgManagerWithCAPIWithFilter build cfgWithCAPI with filter
+func NewClusterCfgManagerWithCAPIWithFilter(dynamicInterface dynamic.Interface, namespaces []string, filter FilterHandler) api.ClusterConfigurationManager {
+	return &cfgWithCAPI{
+		dynamicInterface: dynamicInterface,
+		namespaces:       namespaces,
+		filter:           filter,
+	}
+}
+
+func (ca *cfgWithCAPI) GetAll() ([]api.ClusterCfgInfo, error) {
+	ctx, cancel := context.WithTimeout(context.TODO(), listCAPIClusterTimeout)
+	defer cancel()
+
+	namespaces := ca.namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+
+	items := []unstructured.Unstructured{}
+	for _, ns := range namespaces {
+		clusterList, err := ca.dynamicInterface.Resource(CAPIClusterGVR).Namespace(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("get clusterconfiguration with Cluster API failed namespace:%s err:%+v", ns, err)
+		}
+		items = append(items, clusterList.Items...)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].GetNamespace() != items[j].GetNamespace() {
+			return items[i].GetNamespace() < items[j].GetNamespace()
+		}
+		return items[i].GetName() < items[j].GetName()
+	})
+
+	list := make([]api.ClusterCfgInfo, 0, len(items))
+	names := map[string]string{}
+	for i := range items {
+		item := &items[i]
+		if !capiControlPlaneReady(item) {
+			klog.V(4).Infof("Cluster API cluster %s/%s control plane not ready, skip", item.GetNamespace(), item.GetName())
+			continue
+		}
+		if ns, ok := names[item.GetName()]; ok {
+			klog.Warningf("Cluster API cluster %s/%s name conflict with namespace %s, skip", item.GetNamespace(), item.GetName(), ns)
+			continue
+		}
+
+		kubecfg, err := ca.getKubeconfig(ctx, item.GetNamespace(), item.GetName())
+		if err != nil {
+			// the secret may not be generated yet
+			klog.Errorf("Cluster API cluster %s/%s get kubeconfig failed %+v", item.GetNamespace(), item.GetName(), err)
+			continue
+		}
+		names[item.GetName()] = item.GetNamespace()
+		list = append(list, BuildClusterCfgInfoWithMetadata(item.GetName(), api.KubeConfigTypeRawString, kubecfg, "", item.GetLabels(), item.GetAnnotations()))
+	}
+
+	list = filterClusterInfo(list, ca.filter)
+	return list, nil
+}
+
+// getKubeconfig read <cluster>-kubeconfig secret value
+func (ca *cfgWithCAPI) getKubeconfig(ctx context.Context, namespace, name string) (string, error) {
+	secret, err := ca.dynamicInterface.Resource(secretGVR).Namespace(namespace).Get(ctx, name+CAPIKubeconfigSecretSuffix, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	value, ok, err := unstructured.NestedString(secret.Object, "data", CAPIKubeconfigSecretKey)
+	if err != nil || !ok {
+		return "", fmt.Errorf("secret %s not found data key %s", secret.GetName(), CAPIKubeconfigSecretKey)
+	}
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", fmt.Errorf("secret %s decode data key %s failed %+v", secret.GetName(), CAPIKubeconfigSecretKey, err)
+	}
+	return string(data), nil
+}
+
+// capiControlPlaneReady check status.controlPlaneReady or ControlPlaneReady condition
+func capiControlPlaneReady(item *unstructured.Unstructured) bool {
+	if ready, ok, _ := unstructured.NestedBool(item.Object, "status", "controlPlaneReady"); ok {
+		return ready
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(item.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if condition["type"] == "ControlPlaneReady" {
+			return condition["status"] == string(metav1.ConditionTrue)
+		}
+	}
+	return false
+}
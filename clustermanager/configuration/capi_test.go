@@ -0,0 +1,78 @@
+package configuration
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func buildCAPICluster(namespace, name string, status map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "cluster.x-k8s.io/v1beta1",
+		"kind":       "Cluster",
+		"metadata": map[string]interface{}{
+			"namespace": namespace,
+			"name":      name,
+			"labels":    map[string]interface{}{"env": "prod"},
+		},
+	}}
+	if status != nil {
+		obj.Object["status"] = status
+	}
+	return obj
+}
+
+func buildCAPISecret(namespace, name, value string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata": map[string]interface{}{
+			"namespace": namespace,
+			"name":      name + CAPIKubeconfigSecretSuffix,
+		},
+		"data": map[string]interface{}{
+			CAPIKubeconfigSecretKey: base64.StdEncoding.EncodeToString([]byte(value)),
+		},
+	}}
+}
+
+func TestNewClusterCfgManagerWithCAPI(t *testing.T) {
+	dynamicInterface := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{CAPIClusterGVR: "ClusterList"},
+		buildCAPICluster("ns-a", "ready", map[string]interface{}{"controlPlaneReady": true}),
+		buildCAPISecret("ns-a", "ready", "kubeconfig-ready"),
+		buildCAPICluster("ns-a", "condition-ready", map[string]interface{}{
+			"conditions": []interface{}{map[string]interface{}{"type": "ControlPlaneReady", "status": "True"}},
+		}),
+		buildCAPISecret("ns-a", "condition-ready", "kubeconfig-condition-ready"),
+		buildCAPICluster("ns-a", "not-ready", map[string]interface{}{"controlPlaneReady": false}),
+		buildCAPISecret("ns-a", "not-ready", "kubeconfig-not-ready"),
+		buildCAPICluster("ns-a", "no-secret", map[string]interface{}{"controlPlaneReady": true}),
+		buildCAPICluster("ns-b", "other-namespace", map[string]interface{}{"controlPlaneReady": true}),
+		buildCAPISecret("ns-b", "other-namespace", "kubeconfig-other"),
+	)
+
+	cfg := NewClusterCfgManagerWithCAPI(dynamicInterface, []string{"ns-a"})
+	list, err := cfg.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].GetName() != "condition-ready" || list[1].GetName() != "ready" {
+		t.Fatalf("expect condition-ready and ready, but got %+v", list)
+	}
+	if list[1].GetKubeConfig() != "kubeconfig-ready" || GetClusterLabels(list[1])["env"] != "prod" {
+		t.Errorf("unexpect cluster ready kubeconfig %s labels %+v", list[1].GetKubeConfig(), GetClusterLabels(list[1]))
+	}
+
+	list, err = NewClusterCfgManagerWithCAPI(dynamicInterface, nil).GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 {
+		t.Errorf("expect 3 clusters with all namespaces, but got %d", len(list))
+	}
+}
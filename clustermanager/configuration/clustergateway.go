@@ -2,6 +2,8 @@ package configuration
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	clustetgatewayv1aplpha1 "github.com/oam-dev/cluster-gateway/pkg/apis/cluster/v1alpha1"
 	"github.com/symcn/api"
@@ -9,13 +11,41 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/klog/v2"
 )
 
+// ClusterGatewayMode how to connect the ClusterGateway clusters
+type ClusterGatewayMode string
+
+// ClusterGatewayModeProxy connect through cluster-gateway with hub configuration, use with client.BuildGatewayClient
+// ClusterGatewayModeDirect connect the apiserver with ClusterGatewaySpec.Access directly
+const (
+	ClusterGatewayModeProxy  ClusterGatewayMode = "Proxy"
+	ClusterGatewayModeDirect ClusterGatewayMode = "Direct"
+)
+
+// ClusterGatewayOptions options of ClusterGateway configuration manager
+type ClusterGatewayOptions struct {
+	// Mode default ClusterGatewayModeProxy
+	Mode ClusterGatewayMode
+	// HubClusterCfg hub cluster configuration, required with ClusterGatewayModeProxy
+	HubClusterCfg api.ClusterCfgInfo
+	// SkipUnhealthy skip the clusters which ClusterGatewayStatus is not healthy
+	SkipUnhealthy bool
+	Filter        FilterHandler
+}
+
 type cfgWithClusterGateway struct {
 	dynamicInterface dynamic.Interface
 	gvr              schema.GroupVersionResource
 	cfg              api.ClusterCfgInfo
 	filter           FilterHandler
+	mode             ClusterGatewayMode
+	skipUnhealthy    bool
 }
 
 func NewClusterCfgManagerWithGateway(dyanamicInterface dynamic.Interface, cfg api.ClusterCfgInfo) api.ClusterConfigurationManager {
@@ -35,6 +65,36 @@ func NewClusterCfgManagerWithGatewayWithFilter(dyanamicInterface dynamic.Interfa
 	}
 }
 
+// NewClusterCfgManagerWithGatewayOptions build cfgWithClusterGateway with options
+func NewClusterCfgManagerWithGatewayOptions(dyanamicInterface dynamic.Interface, opts *ClusterGatewayOptions) (api.ClusterConfigurationManager, error) {
+	cgo := ClusterGatewayOptions{}
+	if opts != nil {
+		cgo = *opts
+	}
+	if cgo.Mode == "" {
+		cgo.Mode = ClusterGatewayModeProxy
+	}
+
+	switch cgo.Mode {
+	case ClusterGatewayModeProxy:
+		if cgo.HubClusterCfg == nil {
+			return nil, fmt.Errorf("NewClusterCfgManagerWithGatewayOptions hub cluster configuration is empty with mode %s", cgo.Mode)
+		}
+	case ClusterGatewayModeDirect:
+	default:
+		return nil, fmt.Errorf("NewClusterCfgManagerWithGatewayOptions not support mode %s", cgo.Mode)
+	}
+
+	return &cfgWithClusterGateway{
+		dynamicInterface: dyanamicInterface,
+		gvr:              (&clustetgatewayv1aplpha1.ClusterGateway{}).GetGroupVersionResource(),
+		cfg:              cgo.HubClusterCfg,
+		filter:           cgo.Filter,
+		mode:             cgo.Mode,
+		skipUnhealthy:    cgo.SkipUnhealthy,
+	}, nil
+}
+
 func (cg *cfgWithClusterGateway) GetAll() ([]api.ClusterCfgInfo, error) {
 	list, err := cg.dynamicInterface.Resource(cg.gvr).List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
@@ -42,15 +102,87 @@ func (cg *cfgWithClusterGateway) GetAll() ([]api.ClusterCfgInfo, error) {
 	}
 
 	cfgList := make([]api.ClusterCfgInfo, 0, len(list.Items))
-	clusterGateway := &clustetgatewayv1aplpha1.ClusterGateway{}
 	for _, item := range list.Items {
-		err = runtime.DefaultUnstructuredConverter.FromUnstructured(item.UnstructuredContent(), clusterGateway)
-		if err == nil {
-			cfgList = append(cfgList, BuildClusterCfgInfo(item.GetName(), cg.cfg.GetKubeConfigType(), cg.cfg.GetKubeConfig(), cg.cfg.GetKubeContext()))
+		clusterGateway := &clustetgatewayv1aplpha1.ClusterGateway{}
+		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(item.UnstructuredContent(), clusterGateway); err != nil {
+			klog.Errorf("convert ClusterGateway %s failed %+v", item.GetName(), err)
+			continue
+		}
+		if cg.skipUnhealthy && !clusterGateway.Status.Healthy {
+			klog.V(4).Infof("ClusterGateway %s not healthy %s, skip", clusterGateway.Name, clusterGateway.Status.HealthyReason)
+			continue
+		}
+
+		if cg.mode != ClusterGatewayModeDirect {
+			cfgList = append(cfgList, BuildClusterCfgInfoWithMetadata(item.GetName(), cg.cfg.GetKubeConfigType(), cg.cfg.GetKubeConfig(), cg.cfg.GetKubeContext(), item.GetLabels(), item.GetAnnotations()))
+			continue
 		}
+
+		kubeconfig, err := buildClusterGatewayKubeconfig(clusterGateway)
+		if err != nil {
+			klog.Errorf("ClusterGateway %s build configuration failed %+v", clusterGateway.Name, err)
+			continue
+		}
+		cfgList = append(cfgList, BuildClusterCfgInfoWithMetadata(item.GetName(), api.KubeConfigTypeRawString, kubeconfig, item.GetName(), item.GetLabels(), item.GetAnnotations()))
 	}
 
 	cfgList = filterClusterInfo(cfgList, cg.filter)
 
 	return cfgList, nil
 }
+
+// Notify implements ClusterCfgNotifier, watch all ClusterGateways
+func (cg *cfgWithClusterGateway) Notify(ctx context.Context) (<-chan ClusterCfgEvent, error) {
+	informer := dynamicinformer.NewFilteredDynamicInformer(cg.dynamicInterface, cg.gvr, metav1.NamespaceAll, 0, cache.Indexers{}, nil)
+	return notifyWithInformer(ctx, informer.Informer())
+}
+
+// buildClusterGatewayKubeconfig build raw kubeconfig with ClusterGatewaySpec.Access, only support Const endpoint
+func buildClusterGatewayKubeconfig(cg *clustetgatewayv1aplpha1.ClusterGateway) (string, error) {
+	access := cg.Spec.Access
+	if access.Endpoint == nil || access.Endpoint.Type != clustetgatewayv1aplpha1.ClusterEndpointTypeConst || access.Endpoint.Const == nil {
+		return "", errors.New("endpoint is not Const, can't connect directly")
+	}
+	if access.Endpoint.Const.Address == "" {
+		return "", errors.New("endpoint address is empty")
+	}
+	if access.Credential == nil {
+		return "", errors.New("credential is empty")
+	}
+
+	cluster := &clientcmdapi.Cluster{
+		Server:                   access.Endpoint.Const.Address,
+		CertificateAuthorityData: access.Endpoint.Const.CABundle,
+	}
+	if access.Endpoint.Const.Insecure != nil {
+		cluster.InsecureSkipTLSVerify = *access.Endpoint.Const.Insecure
+	}
+
+	authInfo := &clientcmdapi.AuthInfo{}
+	switch access.Credential.Type {
+	case clustetgatewayv1aplpha1.CredentialTypeServiceAccountToken:
+		if access.Credential.ServiceAccountToken == "" {
+			return "", errors.New("service account token is empty")
+		}
+		authInfo.Token = access.Credential.ServiceAccountToken
+	case clustetgatewayv1aplpha1.CredentialTypeX509Certificate:
+		if access.Credential.X509 == nil {
+			return "", errors.New("x509 certificate is empty")
+		}
+		authInfo.ClientCertificateData = access.Credential.X509.Certificate
+		authInfo.ClientKeyData = access.Credential.X509.PrivateKey
+	default:
+		return "", fmt.Errorf("not support credential type %s", access.Credential.Type)
+	}
+
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters[cg.Name] = cluster
+	kubeconfig.AuthInfos[cg.Name] = authInfo
+	kubeconfig.Contexts[cg.Name] = &clientcmdapi.Context{Cluster: cg.Name, AuthInfo: cg.Name}
+	kubeconfig.CurrentContext = cg.Name
+	data, err := clientcmd.Write(*kubeconfig)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
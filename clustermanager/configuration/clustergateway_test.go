@@ -1,10 +1,18 @@
 package configuration
 
 import (
+	"context"
 	"os"
 	"testing"
 
+	clustetgatewayv1aplpha1 "github.com/oam-dev/cluster-gateway/pkg/apis/cluster/v1alpha1"
+	"github.com/symcn/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
@@ -98,3 +106,113 @@ func TestNewClusterCfgManagerWithGateway(t *testing.T) {
 		t.Logf("found cluster: %s", cluster.GetName())
 	}
 }
+
+func buildClusterGateway(t *testing.T, name string, healthy bool, access clustetgatewayv1aplpha1.ClusterAccess) *unstructured.Unstructured {
+	gvr := (&clustetgatewayv1aplpha1.ClusterGateway{}).GetGroupVersionResource()
+	cg := &clustetgatewayv1aplpha1.ClusterGateway{
+		TypeMeta: metav1.TypeMeta{APIVersion: gvr.GroupVersion().String(), Kind: "ClusterGateway"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"env": "prod"},
+		},
+		Spec:   clustetgatewayv1aplpha1.ClusterGatewaySpec{Access: access},
+		Status: clustetgatewayv1aplpha1.ClusterGatewayStatus{Healthy: healthy},
+	}
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(cg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &unstructured.Unstructured{Object: obj}
+}
+
+func TestNewClusterCfgManagerWithGatewayOptions(t *testing.T) {
+	constEndpoint := &clustetgatewayv1aplpha1.ClusterEndpoint{
+		Type:  clustetgatewayv1aplpha1.ClusterEndpointTypeConst,
+		Const: &clustetgatewayv1aplpha1.ClusterEndpointConst{Address: "https://cluster:6443", CABundle: []byte("ca")},
+	}
+	gvr := (&clustetgatewayv1aplpha1.ClusterGateway{}).GetGroupVersionResource()
+	dynamicInterface := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{gvr: "ClusterGatewayList"})
+	// create with gvr, the fake tracker guesses resource clustergatewaies with the initial objects
+	for _, obj := range []*unstructured.Unstructured{
+		buildClusterGateway(t, "token", true, clustetgatewayv1aplpha1.ClusterAccess{
+			Endpoint:   constEndpoint,
+			Credential: &clustetgatewayv1aplpha1.ClusterAccessCredential{Type: clustetgatewayv1aplpha1.CredentialTypeServiceAccountToken, ServiceAccountToken: "sa-token"},
+		}),
+		buildClusterGateway(t, "x509", true, clustetgatewayv1aplpha1.ClusterAccess{
+			Endpoint: constEndpoint,
+			Credential: &clustetgatewayv1aplpha1.ClusterAccessCredential{
+				Type: clustetgatewayv1aplpha1.CredentialTypeX509Certificate,
+				X509: &clustetgatewayv1aplpha1.X509{Certificate: []byte("cert"), PrivateKey: []byte("key")},
+			},
+		}),
+		buildClusterGateway(t, "proxy", true, clustetgatewayv1aplpha1.ClusterAccess{
+			Endpoint: &clustetgatewayv1aplpha1.ClusterEndpoint{Type: clustetgatewayv1aplpha1.ClusterEndpointTypeClusterProxy},
+		}),
+		buildClusterGateway(t, "unhealthy", false, clustetgatewayv1aplpha1.ClusterAccess{
+			Endpoint:   constEndpoint,
+			Credential: &clustetgatewayv1aplpha1.ClusterAccessCredential{Type: clustetgatewayv1aplpha1.CredentialTypeServiceAccountToken, ServiceAccountToken: "sa-token"},
+		}),
+	} {
+		if _, err := dynamicInterface.Resource(gvr).Create(context.TODO(), obj, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hub := BuildClusterCfgInfo("hub", api.KubeConfigTypeRawString, "hub-kubeconfig", "hub-context")
+
+	if _, err := NewClusterCfgManagerWithGatewayOptions(dynamicInterface, nil); err == nil {
+		t.Error("proxy mode without hub configuration should be error")
+	}
+
+	t.Run("proxy", func(t *testing.T) {
+		cfg, err := NewClusterCfgManagerWithGatewayOptions(dynamicInterface, &ClusterGatewayOptions{HubClusterCfg: hub, SkipUnhealthy: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		list, err := cfg.GetAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 3 {
+			t.Fatalf("expect 3 healthy clusters, but got %d", len(list))
+		}
+		for _, info := range list {
+			if info.GetKubeConfig() != "hub-kubeconfig" || GetClusterLabels(info)["env"] != "prod" {
+				t.Errorf("cluster %s should use hub configuration with labels", info.GetName())
+			}
+		}
+	})
+
+	t.Run("direct", func(t *testing.T) {
+		cfg, err := NewClusterCfgManagerWithGatewayOptions(dynamicInterface, &ClusterGatewayOptions{Mode: ClusterGatewayModeDirect, SkipUnhealthy: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		list, err := cfg.GetAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 2 {
+			t.Fatalf("expect token and x509 clusters, but got %d", len(list))
+		}
+		for _, info := range list {
+			kubeconfig, err := clientcmd.Load([]byte(info.GetKubeConfig()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kubeconfig.Clusters[info.GetKubeContext()].Server != "https://cluster:6443" {
+				t.Errorf("cluster %s should connect the endpoint directly", info.GetName())
+			}
+			authInfo := kubeconfig.AuthInfos[info.GetKubeContext()]
+			switch info.GetName() {
+			case "token":
+				if authInfo.Token != "sa-token" {
+					t.Errorf("expect service account token, but got %+v", authInfo)
+				}
+			case "x509":
+				if string(authInfo.ClientCertificateData) != "cert" || string(authInfo.ClientKeyData) != "key" {
+					t.Errorf("expect x509 certificate, but got %+v", authInfo)
+				}
+			}
+		}
+	})
+}
@@ -7,6 +7,8 @@ type clusterCfgInfo struct {
 	kubeConfigType api.KubeConfigType
 	kubeConfig     string
 	kubeContext    string
+	labels         map[string]string
+	annotations    map[string]string
 }
 
 // BuildClusterCfgInfo build api.ClusterCfgInfo
@@ -35,6 +37,14 @@ func (c *clusterCfgInfo) GetKubeContext() string {
 	return c.kubeContext
 }
 
+func (c *clusterCfgInfo) GetLabels() map[string]string {
+	return c.labels
+}
+
+func (c *clusterCfgInfo) GetAnnotations() map[string]string {
+	return c.annotations
+}
+
 // BuildDefaultClusterCfgInfo BuildDefaultClusterCfgInfo with default Kubernetes configuration
 // use default ~/.kube/config or Kubernetes cluster internal config
 func BuildDefaultClusterCfgInfo(name string) api.ClusterCfgInfo {
@@ -0,0 +1,256 @@
+package configuration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/symcn/api"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+)
+
+// AnnotationClusterSource the source name of cluster which merged by composite manager
+// AnnotationClusterOriginalName the name before prefixed by ConflictPrefix, such as the ClusterGateway name
+const (
+	AnnotationClusterSource       = "symcn.io/cluster-source"
+	AnnotationClusterOriginalName = "symcn.io/cluster-original-name"
+)
+
+// ConflictPolicy how to handle the same cluster name from different sources
+type ConflictPolicy string
+
+// ConflictError GetAll returns error
+// ConflictFirstWins keep the cluster of the higher precedence source
+// ConflictPrefix rename the cluster of the lower precedence source as <source>-<name>,
+// the original name kept with AnnotationClusterOriginalName
+const (
+	ConflictError     ConflictPolicy = "Error"
+	ConflictFirstWins ConflictPolicy = "FirstWins"
+	ConflictPrefix    ConflictPolicy = "Prefix"
+)
+
+// CompositeSource named source of composite manager
+type CompositeSource struct {
+	Name    string
+	Manager api.ClusterConfigurationManager
+}
+
+// SourceStatus the last GetAll result of source
+type SourceStatus struct {
+	Name string
+	// Clusters the number of clusters returned by source, keep the last successful result when failed
+	Clusters    int
+	Err         error
+	LastSuccess time.Time
+}
+
+// CompositeStatus optional interface of composite manager, returns status of each source
+type CompositeStatus interface {
+	GetSourceStatus() []SourceStatus
+}
+
+// cfgWithComposite merge clusters of sources, the former source has higher precedence
+type cfgWithComposite struct {
+	sources []CompositeSource
+	policy  ConflictPolicy
+	filter  FilterHandler
+
+	l      sync.Mutex
+	cached map[string][]api.ClusterCfgInfo
+	status map[string]*SourceStatus
+}
+
+// NewClusterCfgManagerWithComposite build cfgWithComposite, the former source has higher precedence
+func NewClusterCfgManagerWithComposite(policy ConflictPolicy, sources ...CompositeSource) (api.ClusterConfigurationManager, error) {
+	return NewClusterCfgManagerWithCompositeWithFilter(policy, nil, sources...)
+}
+
+// NewClusterCfgManagerWithCompositeWithFilter build cfgWithComposite with filter
+func NewClusterCfgManagerWithCompositeWithFilter(policy ConflictPolicy, filter FilterHandler, sources ...CompositeSource) (api.ClusterConfigurationManager, error) {
+	if len(sources) == 0 {
+		return nil, errors.New("NewClusterCfgManagerWithComposite sources is empty")
+	}
+	if policy == "" {
+		policy = ConflictError
+	}
+	if policy != ConflictError && policy != ConflictFirstWins && policy != ConflictPrefix {
+		return nil, fmt.Errorf("NewClusterCfgManagerWithComposite not support conflict policy %s", policy)
+	}
+
+	names := map[string]struct{}{}
+	for _, source := range sources {
+		if source.Name == "" || source.Manager == nil {
+			return nil, errors.New("NewClusterCfgManagerWithComposite source name and manager must not be empty")
+		}
+		if _, ok := names[source.Name]; ok {
+			return nil, fmt.Errorf("NewClusterCfgManagerWithComposite source %s duplicated", source.Name)
+		}
+		names[source.Name] = struct{}{}
+	}
+
+	return &cfgWithComposite{
+		sources: sources,
+		policy:  policy,
+		filter:  filter,
+		cached:  map[string][]api.ClusterCfgInfo{},
+		status:  map[string]*SourceStatus{},
+	}, nil
+}
+
+// GetAll merge clusters of all sources, the failed source use its last successful result,
+// returns error only when all sources failed or name conflict with ConflictError
+func (cc *cfgWithComposite) GetAll() ([]api.ClusterCfgInfo, error) {
+	cc.l.Lock()
+	defer cc.l.Unlock()
+
+	var errs []error
+	for _, source := range cc.sources {
+		status, ok := cc.status[source.Name]
+		if !ok {
+			status = &SourceStatus{Name: source.Name}
+			cc.status[source.Name] = status
+		}
+
+		list, err := source.Manager.GetAll()
+		if err != nil {
+			klog.Errorf("composite source %s get clusterconfiguration failed, use last result %+v", source.Name, err)
+			status.Err = err
+			errs = append(errs, fmt.Errorf("source %s: %+v", source.Name, err))
+			continue
+		}
+		status.Err = nil
+		status.Clusters = len(list)
+		status.LastSuccess = time.Now()
+		cc.cached[source.Name] = list
+	}
+	if len(errs) == len(cc.sources) {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+
+	result := []api.ClusterCfgInfo{}
+	owners := map[string]string{}
+	for _, source := range cc.sources {
+		for _, info := range cc.cached[source.Name] {
+			name := info.GetName()
+			if owner, ok := owners[name]; ok {
+				switch cc.policy {
+				case ConflictError:
+					return nil, fmt.Errorf("cluster %s conflict with source %s and %s", name, owner, source.Name)
+				case ConflictFirstWins:
+					klog.Warningf("cluster %s of source %s conflict with source %s, ignored", name, source.Name, owner)
+					continue
+				case ConflictPrefix:
+					name = source.Name + "-" + name
+					if _, ok = owners[name]; ok {
+						return nil, fmt.Errorf("cluster %s conflict with source %s after prefixed", name, owners[name])
+					}
+				}
+			}
+			owners[name] = source.Name
+			result = append(result, withClusterSource(info, name, source.Name))
+		}
+	}
+
+	result = filterClusterInfo(result, cc.filter)
+	return result, nil
+}
+
+// GetSourceStatus implements CompositeStatus
+func (cc *cfgWithComposite) GetSourceStatus() []SourceStatus {
+	cc.l.Lock()
+	defer cc.l.Unlock()
+
+	list := make([]SourceStatus, 0, len(cc.sources))
+	for _, source := range cc.sources {
+		if status, ok := cc.status[source.Name]; ok {
+			list = append(list, *status)
+			continue
+		}
+		list = append(list, SourceStatus{Name: source.Name})
+	}
+	return list
+}
+
+// Notify implements ClusterCfgNotifier, merge the notifications of sources which implement it
+func (cc *cfgWithComposite) Notify(ctx context.Context) (<-chan ClusterCfgEvent, error) {
+	chList := []<-chan ClusterCfgEvent{}
+	for _, source := range cc.sources {
+		notifier, ok := source.Manager.(ClusterCfgNotifier)
+		if !ok {
+			continue
+		}
+		ch, err := notifier.Notify(ctx)
+		if err != nil {
+			// polling the source
+			klog.Errorf("composite source %s notify failed %+v", source.Name, err)
+			continue
+		}
+		chList = append(chList, ch)
+	}
+
+	merged := make(chan ClusterCfgEvent, defaultNotifyBufferSize)
+	wg := sync.WaitGroup{}
+	for _, ch := range chList {
+		wg.Add(1)
+		go func(ch <-chan ClusterCfgEvent) {
+			defer wg.Done()
+			for event := range ch {
+				select {
+				case merged <- event:
+				case <-ctx.Done():
+				}
+			}
+		}(ch)
+	}
+	go func() {
+		wg.Wait()
+		<-ctx.Done()
+		close(merged)
+	}()
+	return merged, nil
+}
+
+// ReportClusterStatus implements ClusterStatusReporter, forward to the source of cluster which implements it
+func (cc *cfgWithComposite) ReportClusterStatus(ctx context.Context, clusterInfo api.ClusterCfgInfo, status ClusterStatus) error {
+	sourceName := GetClusterSource(clusterInfo)
+	for _, source := range cc.sources {
+		if source.Name != sourceName {
+			continue
+		}
+		reporter, ok := source.Manager.(ClusterStatusReporter)
+		if !ok {
+			return nil
+		}
+		return reporter.ReportClusterStatus(ctx, clusterInfo, status)
+	}
+	return fmt.Errorf("cluster %s source %s not found", clusterInfo.GetName(), sourceName)
+}
+
+// GetClusterSource returns the source name of cluster which merged by composite manager
+func GetClusterSource(clusterInfo api.ClusterCfgInfo) string {
+	return GetClusterAnnotations(clusterInfo)[AnnotationClusterSource]
+}
+
+// GetClusterOriginalName returns the name before prefixed by composite manager, returns GetName when not prefixed
+func GetClusterOriginalName(clusterInfo api.ClusterCfgInfo) string {
+	if name, ok := GetClusterAnnotations(clusterInfo)[AnnotationClusterOriginalName]; ok && name != "" {
+		return name
+	}
+	return clusterInfo.GetName()
+}
+
+// withClusterSource copy cluster info with name and source annotation
+func withClusterSource(info api.ClusterCfgInfo, name, source string) api.ClusterCfgInfo {
+	annotations := copyStringMap(GetClusterAnnotations(info))
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[AnnotationClusterSource] = source
+	if name != info.GetName() {
+		annotations[AnnotationClusterOriginalName] = GetClusterOriginalName(info)
+	}
+	return BuildClusterCfgInfoWithMetadata(name, info.GetKubeConfigType(), info.GetKubeConfig(), info.GetKubeContext(), GetClusterLabels(info), annotations)
+}
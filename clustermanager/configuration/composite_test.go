@@ -0,0 +1,116 @@
+package configuration
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/symcn/api"
+)
+
+func TestCompositeClusterCfgManager(t *testing.T) {
+	var sourceBErr error
+	buildSource := func(names ...string) *FakeConfiguration {
+		return &FakeConfiguration{
+			GetAllFunc: func() ([]api.ClusterCfgInfo, error) {
+				list := []api.ClusterCfgInfo{}
+				for _, name := range names {
+					list = append(list, BuildClusterCfgInfoWithMetadata(name, api.KubeConfigTypeRawString, "", "", map[string]string{"env": "prod"}, nil))
+				}
+				return list, nil
+			},
+		}
+	}
+	sourceA := buildSource("cluster-1", "cluster-2")
+	sourceB := &FakeConfiguration{
+		GetAllFunc: func() ([]api.ClusterCfgInfo, error) {
+			if sourceBErr != nil {
+				return nil, sourceBErr
+			}
+			return []api.ClusterCfgInfo{BuildClusterCfgInfo("cluster-2", api.KubeConfigTypeRawString, "", ""), BuildClusterCfgInfo("cluster-3", api.KubeConfigTypeRawString, "", "")}, nil
+		},
+	}
+	sources := []CompositeSource{{Name: "a", Manager: sourceA}, {Name: "b", Manager: sourceB}}
+
+	getNames := func(list []api.ClusterCfgInfo) map[string]string {
+		names := map[string]string{}
+		for _, info := range list {
+			names[info.GetName()] = GetClusterSource(info)
+		}
+		return names
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, err := NewClusterCfgManagerWithComposite(ConflictError); err == nil {
+			t.Error("empty sources should be error")
+		}
+		if _, err := NewClusterCfgManagerWithComposite("unknown", sources...); err == nil {
+			t.Error("unknown policy should be error")
+		}
+		if _, err := NewClusterCfgManagerWithComposite(ConflictError, sources[0], sources[0]); err == nil {
+			t.Error("duplicated source should be error")
+		}
+	})
+
+	t.Run("conflict error", func(t *testing.T) {
+		cfg, _ := NewClusterCfgManagerWithComposite(ConflictError, sources...)
+		if _, err := cfg.GetAll(); err == nil {
+			t.Error("cluster-2 conflict should be error")
+		}
+	})
+
+	t.Run("first wins", func(t *testing.T) {
+		cfg, _ := NewClusterCfgManagerWithComposite(ConflictFirstWins, sources...)
+		list, err := cfg.GetAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := getNames(list)
+		if len(names) != 3 || names["cluster-2"] != "a" || names["cluster-3"] != "b" {
+			t.Errorf("unexpect clusters %+v", names)
+		}
+		if GetClusterLabels(list[0])["env"] != "prod" {
+			t.Error("labels should be kept")
+		}
+	})
+
+	t.Run("prefix and tolerate failure", func(t *testing.T) {
+		cfg, _ := NewClusterCfgManagerWithComposite(ConflictPrefix, sources...)
+		list, err := cfg.GetAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := getNames(list)
+		if len(names) != 4 || names["b-cluster-2"] != "b" {
+			t.Fatalf("unexpect clusters %+v", names)
+		}
+		for _, info := range list {
+			if info.GetName() == "b-cluster-2" && GetClusterOriginalName(info) != "cluster-2" {
+				t.Errorf("original name should be cluster-2, but got %s", GetClusterOriginalName(info))
+			}
+		}
+
+		// source b failed, keep the last result
+		sourceBErr = errors.New("connection refused")
+		defer func() { sourceBErr = nil }()
+		list, err = cfg.GetAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 4 {
+			t.Errorf("failed source should keep last result, but got %+v", getNames(list))
+		}
+		status := cfg.(CompositeStatus).GetSourceStatus()
+		if len(status) != 2 || status[0].Err != nil || status[1].Err == nil || status[1].Clusters != 2 {
+			t.Errorf("unexpect source status %+v", status)
+		}
+	})
+
+	t.Run("all failed", func(t *testing.T) {
+		sourceBErr = errors.New("connection refused")
+		defer func() { sourceBErr = nil }()
+		cfg, _ := NewClusterCfgManagerWithComposite(ConflictFirstWins, sources[1])
+		if _, err := cfg.GetAll(); err == nil {
+			t.Error("all sources failed should be error")
+		}
+	})
+}
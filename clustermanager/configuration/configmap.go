@@ -8,8 +8,12 @@ import (
 
 	"github.com/symcn/api"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 )
 
 var (
@@ -54,14 +58,7 @@ func (cc *cfgWithConfigmap) GetAll() ([]api.ClusterCfgInfo, error) {
 	ctx, cancel := context.WithTimeout(context.TODO(), listConfigmapTimeout)
 	defer cancel()
 
-	labelSelectors := make([]string, 0, len(cc.label))
-	for k, v := range cc.label {
-		if k != "" && v != "" {
-			labelSelectors = append(labelSelectors, fmt.Sprintf("%s=%s", k, v))
-		}
-	}
-
-	cmlist, err := cc.kubeInterface.CoreV1().ConfigMaps(cc.namespace).List(ctx, metav1.ListOptions{LabelSelector: strings.Join(labelSelectors, ",")})
+	cmlist, err := cc.kubeInterface.CoreV1().ConfigMaps(cc.namespace).List(ctx, metav1.ListOptions{LabelSelector: cc.labelSelector()})
 	if err != nil {
 		return nil, fmt.Errorf("get clusterconfiguration with configmap failed namespace:%s label:%+v err:%+v", cc.namespace, cc.label, err)
 	}
@@ -71,6 +68,21 @@ func (cc *cfgWithConfigmap) GetAll() ([]api.ClusterCfgInfo, error) {
 	return list, nil
 }
 
+func (cc *cfgWithConfigmap) labelSelector() string {
+	return buildLabelSelector(cc.label)
+}
+
+// buildLabelSelector join labels as selector, ignore empty key or value
+func buildLabelSelector(label map[string]string) string {
+	labelSelectors := make([]string, 0, len(label))
+	for k, v := range label {
+		if k != "" && v != "" {
+			labelSelectors = append(labelSelectors, fmt.Sprintf("%s=%s", k, v))
+		}
+	}
+	return strings.Join(labelSelectors, ",")
+}
+
 // configmap2ClusterCfgInfo configmaplist to clusterconfiguration info
 func configmap2ClusterCfgInfo(cmlist *v1.ConfigMapList, dataKey, statusKey string) []api.ClusterCfgInfo {
 	list := make([]api.ClusterCfgInfo, 0, len(cmlist.Items))
@@ -87,8 +99,33 @@ func configmap2ClusterCfgInfo(cmlist *v1.ConfigMapList, dataKey, statusKey strin
 			// otherwise disconnected
 			continue
 		}
-		list = append(list, BuildClusterCfgInfo(cm.Name, api.KubeConfigTypeRawString, kubecfg, ""))
+		list = append(list, BuildClusterCfgInfoWithMetadata(cm.Name, api.KubeConfigTypeRawString, kubecfg, "", withoutClusterStatus(cm.Labels), withoutClusterStatus(cm.Annotations)))
 	}
 
 	return list
 }
+
+// Notify implements ClusterCfgNotifier, watch the configmaps with namespace and label
+func (cc *cfgWithConfigmap) Notify(ctx context.Context) (<-chan ClusterCfgEvent, error) {
+	informer := coreinformers.NewFilteredConfigMapInformer(cc.kubeInterface, cc.namespace, 0, cache.Indexers{}, func(opts *metav1.ListOptions) {
+		opts.LabelSelector = cc.labelSelector()
+	})
+	return notifyWithInformer(ctx, informer)
+}
+
+// ReportClusterStatus implements ClusterStatusReporter, patch labels and annotations of the configmap
+func (cc *cfgWithConfigmap) ReportClusterStatus(ctx context.Context, clusterInfo api.ClusterCfgInfo, status ClusterStatus) error {
+	patch, err := statusMergePatch(status)
+	if err != nil {
+		return err
+	}
+	_, err = cc.kubeInterface.CoreV1().ConfigMaps(cc.namespace).Patch(ctx, GetClusterOriginalName(clusterInfo), types.MergePatchType, patch, metav1.PatchOptions{})
+	if apierrors.IsNotFound(err) {
+		// removed with the cluster, nothing to report
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("report cluster %s status to configmap failed %+v", clusterInfo.GetName(), err)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package configuration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+
+	"github.com/symcn/api"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/klog/v2"
+)
+
+// AnnotationExpandedFrom the cluster name before expanded by context
+const AnnotationExpandedFrom = "symcn.io/expanded-from"
+
+// ContextExpandOptions options of expanding kubeconfig contexts
+type ContextExpandOptions struct {
+	// Glob match context names with path.Match, such as prod-*
+	Glob string
+	// Regexp match context names, can't be used with Glob
+	Regexp string
+	// PrefixClusterName name the expanded cluster as <cluster>-<context>, default <context>
+	PrefixClusterName bool
+}
+
+// cfgWithContextExpand decorate manager, expand each kubeconfig into one cluster per context
+type cfgWithContextExpand struct {
+	manager           api.ClusterConfigurationManager
+	glob              string
+	re                *regexp.Regexp
+	prefixClusterName bool
+}
+
+// NewClusterCfgManagerWithContextExpand build cfgWithContextExpand, the clusters with
+// KubeContext or unsupported kubeconfig type are kept as they are, opts is nil expands all contexts
+func NewClusterCfgManagerWithContextExpand(manager api.ClusterConfigurationManager, opts *ContextExpandOptions) (api.ClusterConfigurationManager, error) {
+	if manager == nil {
+		return nil, errors.New("NewClusterCfgManagerWithContextExpand manager is nil")
+	}
+	ce := &cfgWithContextExpand{manager: manager}
+	if opts == nil {
+		return ce, nil
+	}
+
+	if opts.Glob != "" && opts.Regexp != "" {
+		return nil, errors.New("NewClusterCfgManagerWithContextExpand glob and regexp can't be used together")
+	}
+	if opts.Glob != "" {
+		if _, err := path.Match(opts.Glob, ""); err != nil {
+			return nil, fmt.Errorf("NewClusterCfgManagerWithContextExpand invalid glob %s %+v", opts.Glob, err)
+		}
+		ce.glob = opts.Glob
+	}
+	if opts.Regexp != "" {
+		re, err := regexp.Compile(opts.Regexp)
+		if err != nil {
+			return nil, fmt.Errorf("NewClusterCfgManagerWithContextExpand invalid regexp %s %+v", opts.Regexp, err)
+		}
+		ce.re = re
+	}
+	ce.prefixClusterName = opts.PrefixClusterName
+	return ce, nil
+}
+
+func (ce *cfgWithContextExpand) GetAll() ([]api.ClusterCfgInfo, error) {
+	list, err := ce.manager.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]api.ClusterCfgInfo, 0, len(list))
+	names := map[string]string{}
+	add := func(info api.ClusterCfgInfo, from string) {
+		if exist, ok := names[info.GetName()]; ok {
+			klog.Warningf("expanded cluster %s of %s conflict with %s, skip", info.GetName(), from, exist)
+			return
+		}
+		names[info.GetName()] = from
+		result = append(result, info)
+	}
+
+	for _, info := range list {
+		if info.GetKubeContext() != "" {
+			add(info, info.GetName())
+			continue
+		}
+
+		kubeconfig, err := loadKubeconfig(info)
+		if err != nil {
+			// keep the cluster, the build failure reported by multiclient
+			klog.Errorf("cluster %s load kubeconfig failed, skip expand %+v", info.GetName(), err)
+			add(info, info.GetName())
+			continue
+		}
+		if kubeconfig == nil {
+			// such as in-cluster config
+			add(info, info.GetName())
+			continue
+		}
+
+		contexts := make([]string, 0, len(kubeconfig.Contexts))
+		for name := range kubeconfig.Contexts {
+			if ce.match(name) {
+				contexts = append(contexts, name)
+			}
+		}
+		sort.Strings(contexts)
+
+		annotations := copyStringMap(GetClusterAnnotations(info))
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[AnnotationExpandedFrom] = info.GetName()
+		// status reported to the source object of kubeconfig
+		annotations[AnnotationClusterOriginalName] = GetClusterOriginalName(info)
+		for _, kubeContext := range contexts {
+			name := kubeContext
+			if ce.prefixClusterName {
+				name = info.GetName() + "-" + kubeContext
+			}
+			add(BuildClusterCfgInfoWithMetadata(name, info.GetKubeConfigType(), info.GetKubeConfig(), kubeContext, GetClusterLabels(info), annotations), info.GetName())
+		}
+	}
+	return result, nil
+}
+
+// Notify implements ClusterCfgNotifier when the decorated manager implements it
+func (ce *cfgWithContextExpand) Notify(ctx context.Context) (<-chan ClusterCfgEvent, error) {
+	notifier, ok := ce.manager.(ClusterCfgNotifier)
+	if !ok {
+		return nil, errors.New("decorated cluster configuration manager not support notify")
+	}
+	return notifier.Notify(ctx)
+}
+
+// ReportClusterStatus implements ClusterStatusReporter when the decorated manager implements it,
+// the clusters expanded from one kubeconfig report to the same source object
+func (ce *cfgWithContextExpand) ReportClusterStatus(ctx context.Context, clusterInfo api.ClusterCfgInfo, status ClusterStatus) error {
+	reporter, ok := ce.manager.(ClusterStatusReporter)
+	if !ok {
+		return nil
+	}
+	return reporter.ReportClusterStatus(ctx, clusterInfo, status)
+}
+
+func (ce *cfgWithContextExpand) match(kubeContext string) bool {
+	if ce.re != nil {
+		return ce.re.MatchString(kubeContext)
+	}
+	if ce.glob != "" {
+		ok, _ := path.Match(ce.glob, kubeContext)
+		return ok
+	}
+	return true
+}
+
+// loadKubeconfig load raw or file kubeconfig, returns nil with other types
+func loadKubeconfig(info api.ClusterCfgInfo) (*clientcmdapi.Config, error) {
+	switch info.GetKubeConfigType() {
+	case api.KubeConfigTypeRawString:
+		return clientcmd.Load([]byte(info.GetKubeConfig()))
+	case api.KubeConfigTypeFile:
+		if info.GetKubeConfig() == "" {
+			// default ~/.kube/config
+			return clientcmd.NewDefaultClientConfigLoadingRules().Load()
+		}
+		return clientcmd.LoadFromFile(info.GetKubeConfig())
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,117 @@
+package configuration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/symcn/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func buildMultiContextKubeconfig(t *testing.T, contexts ...string) string {
+	cfg := clientcmdapi.NewConfig()
+	for _, name := range contexts {
+		cfg.Clusters[name] = &clientcmdapi.Cluster{Server: "https://" + name}
+		cfg.AuthInfos[name] = &clientcmdapi.AuthInfo{Token: "token"}
+		cfg.Contexts[name] = &clientcmdapi.Context{Cluster: name, AuthInfo: name}
+	}
+	data, err := clientcmd.Write(*cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestContextExpand(t *testing.T) {
+	kubeconfig := buildMultiContextKubeconfig(t, "prod-eu", "prod-us", "test")
+	source := &FakeConfiguration{
+		GetAllFunc: func() ([]api.ClusterCfgInfo, error) {
+			return []api.ClusterCfgInfo{
+				BuildClusterCfgInfoWithMetadata("engineers", api.KubeConfigTypeRawString, kubeconfig, "", map[string]string{"team": "a"}, nil),
+				BuildClusterCfgInfo("fixed", api.KubeConfigTypeRawString, kubeconfig, "test"),
+				BuildClusterCfgInfo("invalid", api.KubeConfigTypeRawString, "invalid kubeconfig", ""),
+			}, nil
+		},
+	}
+
+	getNames := func(opts *ContextExpandOptions) []string {
+		cfg, err := NewClusterCfgManagerWithContextExpand(source, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		list, err := cfg.GetAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for _, info := range list {
+			names = append(names, info.GetName()+"@"+info.GetKubeContext())
+		}
+		return names
+	}
+
+	names := getNames(nil)
+	if len(names) != 5 || names[0] != "prod-eu@prod-eu" || names[3] != "fixed@test" || names[4] != "invalid@" {
+		t.Errorf("expect all contexts expanded and invalid kept, but got %v", names)
+	}
+
+	names = getNames(&ContextExpandOptions{Glob: "prod-*", PrefixClusterName: true})
+	if len(names) != 4 || names[0] != "engineers-prod-eu@prod-eu" || names[1] != "engineers-prod-us@prod-us" {
+		t.Errorf("expect prod contexts expanded with prefix, but got %v", names)
+	}
+
+	names = getNames(&ContextExpandOptions{Regexp: "^test$"})
+	if len(names) != 3 || names[0] != "test@test" || names[1] != "fixed@test" {
+		t.Errorf("expect test context expanded, but got %v", names)
+	}
+
+	cfg, _ := NewClusterCfgManagerWithContextExpand(source, &ContextExpandOptions{Glob: "prod-eu"})
+	list, _ := cfg.GetAll()
+	if GetClusterLabels(list[0])["team"] != "a" || GetClusterAnnotations(list[0])[AnnotationExpandedFrom] != "engineers" {
+		t.Errorf("expanded cluster should keep metadata, but got %+v %+v", GetClusterLabels(list[0]), GetClusterAnnotations(list[0]))
+	}
+
+	if _, err := NewClusterCfgManagerWithContextExpand(source, &ContextExpandOptions{Glob: "a", Regexp: "b"}); err == nil {
+		t.Error("glob and regexp together should be error")
+	}
+	if _, err := NewClusterCfgManagerWithContextExpand(source, &ContextExpandOptions{Regexp: "("}); err == nil {
+		t.Error("invalid regexp should be error")
+	}
+}
+
+func TestContextExpandReportClusterStatus(t *testing.T) {
+	kubeInterface := fake.NewSimpleClientset(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "engineers"},
+		Data:       map[string]string{"kubeconfig": buildMultiContextKubeconfig(t, "prod-eu", "prod-us")},
+	})
+	cfg, err := NewClusterCfgManagerWithContextExpand(NewClusterCfgManagerWithCM(kubeInterface, "default", nil, "kubeconfig", "status"), &ContextExpandOptions{PrefixClusterName: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := cfg.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].GetName() != "engineers-prod-eu" || GetClusterOriginalName(list[0]) != "engineers" {
+		t.Fatalf("expect expanded clusters keep original name, but got %+v", list)
+	}
+
+	reporter, ok := cfg.(ClusterStatusReporter)
+	if !ok {
+		t.Fatal("expand manager should implement ClusterStatusReporter")
+	}
+	if err = reporter.ReportClusterStatus(context.TODO(), list[0], ClusterStatus{Connected: true}); err != nil {
+		t.Fatal(err)
+	}
+	cm, err := kubeInterface.CoreV1().ConfigMaps("default").Get(context.TODO(), "engineers", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cm.Labels[LabelClusterConnected] != "true" {
+		t.Errorf("expect status reported to the source configmap, but got %+v", cm.Labels)
+	}
+}
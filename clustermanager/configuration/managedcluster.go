@@ -0,0 +1,154 @@
+package configuration
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/symcn/api"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/klog/v2"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+var (
+	listManagedClusterTimeout = time.Second * 5
+)
+
+// ManagedClusterClaimPrefix cluster claims exposed as annotations with prefix
+const ManagedClusterClaimPrefix = "clusterclaim.symcn.io/"
+
+// ManagedClusterMode how to connect the managed clusters
+type ManagedClusterMode string
+
+// ManagedClusterModeGateway connect through cluster-gateway with hub configuration, use with client.BuildGatewayClient
+// ManagedClusterModeDirect connect the apiserver of ManagedClusterClientConfigs directly
+const (
+	ManagedClusterModeGateway ManagedClusterMode = "Gateway"
+	ManagedClusterModeDirect  ManagedClusterMode = "Direct"
+)
+
+// ManagedClusterOptions options of ManagedCluster configuration manager
+type ManagedClusterOptions struct {
+	// Mode default ManagedClusterModeGateway
+	Mode ManagedClusterMode
+	// HubClusterCfg hub cluster configuration, required with ManagedClusterModeGateway
+	HubClusterCfg api.ClusterCfgInfo
+	// AuthInfo credential of the managed clusters, required with ManagedClusterModeDirect
+	AuthInfo *clientcmdapi.AuthInfo
+	// LabelSelector list ManagedClusters with label selector
+	LabelSelector string
+	Filter        FilterHandler
+}
+
+// cfgWithManagedCluster clusterconfiguration manager with open cluster management ManagedClusters
+type cfgWithManagedCluster struct {
+	dynamicInterface dynamic.Interface
+	opts             ManagedClusterOptions
+}
+
+// NewClusterCfgManagerWithManagedCluster build cfgWithManagedCluster
+func NewClusterCfgManagerWithManagedCluster(dynamicInterface dynamic.Interface, opts *ManagedClusterOptions) (api.ClusterConfigurationManager, error) {
+	mco := ManagedClusterOptions{}
+	if opts != nil {
+		mco = *opts
+	}
+	if mco.Mode == "" {
+		mco.Mode = ManagedClusterModeGateway
+	}
+
+	switch mco.Mode {
+	case ManagedClusterModeGateway:
+		if mco.HubClusterCfg == nil {
+			return nil, fmt.Errorf("NewClusterCfgManagerWithManagedCluster hub cluster configuration is empty with mode %s", mco.Mode)
+		}
+	case ManagedClusterModeDirect:
+		if mco.AuthInfo == nil {
+			return nil, fmt.Errorf("NewClusterCfgManagerWithManagedCluster auth info is empty with mode %s", mco.Mode)
+		}
+	default:
+		return nil, fmt.Errorf("NewClusterCfgManagerWithManagedCluster not support mode %s", mco.Mode)
+	}
+
+	return &cfgWithManagedCluster{
+		dynamicInterface: dynamicInterface,
+		opts:             mco,
+	}, nil
+}
+
+func (cm *cfgWithManagedCluster) GetAll() ([]api.ClusterCfgInfo, error) {
+	ctx, cancel := context.WithTimeout(context.TODO(), listManagedClusterTimeout)
+	defer cancel()
+
+	gvr := clusterv1.GroupVersion.WithResource("managedclusters")
+	list, err := cm.dynamicInterface.Resource(gvr).List(ctx, metav1.ListOptions{LabelSelector: cm.opts.LabelSelector})
+	if err != nil {
+		return nil, fmt.Errorf("get clusterconfiguration with ManagedCluster failed %+v", err)
+	}
+
+	cfgList := make([]api.ClusterCfgInfo, 0, len(list.Items))
+	for _, item := range list.Items {
+		mc := &clusterv1.ManagedCluster{}
+		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(item.UnstructuredContent(), mc); err != nil {
+			klog.Errorf("convert ManagedCluster %s failed %+v", item.GetName(), err)
+			continue
+		}
+		if !mc.Spec.HubAcceptsClient {
+			klog.V(4).Infof("ManagedCluster %s not accepted by hub, skip", mc.Name)
+			continue
+		}
+		if !meta.IsStatusConditionTrue(mc.Status.Conditions, clusterv1.ManagedClusterConditionAvailable) {
+			klog.V(4).Infof("ManagedCluster %s not available, skip", mc.Name)
+			continue
+		}
+
+		info, err := cm.buildClusterCfgInfo(mc)
+		if err != nil {
+			klog.Errorf("ManagedCluster %s build configuration failed %+v", mc.Name, err)
+			continue
+		}
+		cfgList = append(cfgList, info)
+	}
+
+	cfgList = filterClusterInfo(cfgList, cm.opts.Filter)
+	return cfgList, nil
+}
+
+func (cm *cfgWithManagedCluster) buildClusterCfgInfo(mc *clusterv1.ManagedCluster) (api.ClusterCfgInfo, error) {
+	annotations := make(map[string]string, len(mc.Annotations)+len(mc.Status.ClusterClaims))
+	for k, v := range mc.Annotations {
+		annotations[k] = v
+	}
+	for _, claim := range mc.Status.ClusterClaims {
+		annotations[ManagedClusterClaimPrefix+claim.Name] = claim.Value
+	}
+
+	if cm.opts.Mode == ManagedClusterModeGateway {
+		hub := cm.opts.HubClusterCfg
+		return BuildClusterCfgInfoWithMetadata(mc.Name, hub.GetKubeConfigType(), hub.GetKubeConfig(), hub.GetKubeContext(), mc.Labels, annotations), nil
+	}
+
+	if len(mc.Spec.ManagedClusterClientConfigs) == 0 || mc.Spec.ManagedClusterClientConfigs[0].URL == "" {
+		return nil, fmt.Errorf("ManagedCluster %s apiserver address is empty", mc.Name)
+	}
+	clientConfig := mc.Spec.ManagedClusterClientConfigs[0]
+
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters[mc.Name] = &clientcmdapi.Cluster{
+		Server:                   clientConfig.URL,
+		CertificateAuthorityData: clientConfig.CABundle,
+	}
+	kubeconfig.AuthInfos[mc.Name] = cm.opts.AuthInfo.DeepCopy()
+	kubeconfig.Contexts[mc.Name] = &clientcmdapi.Context{Cluster: mc.Name, AuthInfo: mc.Name}
+	kubeconfig.CurrentContext = mc.Name
+	data, err := clientcmd.Write(*kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	return BuildClusterCfgInfoWithMetadata(mc.Name, api.KubeConfigTypeRawString, string(data), mc.Name, mc.Labels, annotations), nil
+}
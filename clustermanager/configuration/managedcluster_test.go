@@ -0,0 +1,98 @@
+package configuration
+
+import (
+	"testing"
+
+	"github.com/symcn/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+func buildManagedCluster(t *testing.T, name string, accepted bool, available metav1.ConditionStatus) *unstructured.Unstructured {
+	mc := &clusterv1.ManagedCluster{
+		TypeMeta: metav1.TypeMeta{APIVersion: clusterv1.GroupVersion.String(), Kind: "ManagedCluster"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"env": "prod"},
+		},
+		Spec: clusterv1.ManagedClusterSpec{
+			HubAcceptsClient:            accepted,
+			ManagedClusterClientConfigs: []clusterv1.ClientConfig{{URL: "https://" + name + ":6443", CABundle: []byte("ca")}},
+		},
+		Status: clusterv1.ManagedClusterStatus{
+			Conditions:    []metav1.Condition{{Type: clusterv1.ManagedClusterConditionAvailable, Status: available}},
+			ClusterClaims: []clusterv1.ManagedClusterClaim{{Name: "region.open-cluster-management.io", Value: "eu"}},
+		},
+	}
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(mc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &unstructured.Unstructured{Object: obj}
+}
+
+func TestNewClusterCfgManagerWithManagedCluster(t *testing.T) {
+	dynamicInterface := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{clusterv1.GroupVersion.WithResource("managedclusters"): "ManagedClusterList"},
+		buildManagedCluster(t, "available", true, metav1.ConditionTrue),
+		buildManagedCluster(t, "not-accepted", false, metav1.ConditionTrue),
+		buildManagedCluster(t, "unavailable", true, metav1.ConditionUnknown),
+	)
+	hub := BuildClusterCfgInfo("hub", api.KubeConfigTypeRawString, "hub-kubeconfig", "hub-context")
+
+	t.Run("invalid options", func(t *testing.T) {
+		if _, err := NewClusterCfgManagerWithManagedCluster(dynamicInterface, nil); err == nil {
+			t.Error("gateway mode without hub configuration should be error")
+		}
+		if _, err := NewClusterCfgManagerWithManagedCluster(dynamicInterface, &ManagedClusterOptions{Mode: ManagedClusterModeDirect}); err == nil {
+			t.Error("direct mode without auth info should be error")
+		}
+	})
+
+	t.Run("gateway", func(t *testing.T) {
+		cfg, err := NewClusterCfgManagerWithManagedCluster(dynamicInterface, &ManagedClusterOptions{HubClusterCfg: hub})
+		if err != nil {
+			t.Fatal(err)
+		}
+		list, err := cfg.GetAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 1 || list[0].GetName() != "available" || list[0].GetKubeConfig() != "hub-kubeconfig" {
+			t.Fatalf("expect available cluster with hub kubeconfig, but got %+v", list)
+		}
+		if GetClusterLabels(list[0])["env"] != "prod" || GetClusterAnnotations(list[0])[ManagedClusterClaimPrefix+"region.open-cluster-management.io"] != "eu" {
+			t.Errorf("unexpect metadata %+v %+v", GetClusterLabels(list[0]), GetClusterAnnotations(list[0]))
+		}
+	})
+
+	t.Run("direct", func(t *testing.T) {
+		cfg, err := NewClusterCfgManagerWithManagedCluster(dynamicInterface, &ManagedClusterOptions{
+			Mode:     ManagedClusterModeDirect,
+			AuthInfo: &clientcmdapi.AuthInfo{Token: "token"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		list, err := cfg.GetAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 1 {
+			t.Fatalf("expect 1 cluster, but got %d", len(list))
+		}
+		kubeconfig, err := clientcmd.Load([]byte(list[0].GetKubeConfig()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kubeconfig.Clusters["available"].Server != "https://available:6443" || kubeconfig.AuthInfos["available"].Token != "token" {
+			t.Errorf("unexpect kubeconfig %+v", kubeconfig)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package configuration
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/symcn/api"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+// MetadataFileSuffix sidecar file of the kubeconfig file in path manager,
+// such as cluster-a.yaml with cluster-a.yaml.meta
+const MetadataFileSuffix = ".meta"
+
+// ClusterMetadata optional interface of api.ClusterCfgInfo, labels and annotations of the cluster
+type ClusterMetadata interface {
+	GetLabels() map[string]string
+	GetAnnotations() map[string]string
+}
+
+// clusterMetadataFile the content of sidecar file, yaml or json
+type clusterMetadataFile struct {
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// BuildClusterCfgInfoWithMetadata build api.ClusterCfgInfo with labels and annotations
+func BuildClusterCfgInfoWithMetadata(name string, kubeConfigType api.KubeConfigType, kubeConfig string, kubeContext string, labels, annotations map[string]string) api.ClusterCfgInfo {
+	return &clusterCfgInfo{
+		name:           name,
+		kubeConfigType: kubeConfigType,
+		kubeConfig:     kubeConfig,
+		kubeContext:    kubeContext,
+		labels:         copyStringMap(labels),
+		annotations:    copyStringMap(annotations),
+	}
+}
+
+// GetClusterLabels returns labels of the cluster, nil when not implement ClusterMetadata
+func GetClusterLabels(clusterInfo api.ClusterCfgInfo) map[string]string {
+	if md, ok := clusterInfo.(ClusterMetadata); ok {
+		return md.GetLabels()
+	}
+	return nil
+}
+
+// GetClusterAnnotations returns annotations of the cluster, nil when not implement ClusterMetadata
+func GetClusterAnnotations(clusterInfo api.ClusterCfgInfo) map[string]string {
+	if md, ok := clusterInfo.(ClusterMetadata); ok {
+		return md.GetAnnotations()
+	}
+	return nil
+}
+
+// MatchSelector check the cluster labels match selector, nil selector matches everything
+func MatchSelector(clusterInfo api.ClusterCfgInfo, selector labels.Selector) bool {
+	if selector == nil {
+		return true
+	}
+	return selector.Matches(labels.Set(GetClusterLabels(clusterInfo)))
+}
+
+// SelectorFilter build FilterHandler with cluster label selector
+func SelectorFilter(selector labels.Selector) FilterHandler {
+	return func(clusterInfo api.ClusterCfgInfo) bool {
+		return MatchSelector(clusterInfo, selector)
+	}
+}
+
+// readMetadataFile read sidecar metadata file, returns empty metadata when not exist
+func readMetadataFile(path string) (*clusterMetadataFile, error) {
+	md := &clusterMetadataFile{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return md, nil
+		}
+		return nil, fmt.Errorf("read metadata file %s err %+v", path, err)
+	}
+	if err = yaml.Unmarshal(data, md); err != nil {
+		return nil, fmt.Errorf("unmarshal metadata file %s err %+v", path, err)
+	}
+	return md, nil
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(m))
+	for k, v := range m {
+		result[k] = v
+	}
+	return result
+}
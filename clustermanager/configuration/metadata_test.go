@@ -0,0 +1,85 @@
+package configuration
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/symcn/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestClusterMetadata(t *testing.T) {
+	t.Run("not implement metadata", func(t *testing.T) {
+		info := NewFakeClusterCfgInfo("", api.KubeConfigTypeRawString, "", "fake")
+		if GetClusterLabels(info) != nil || GetClusterAnnotations(info) != nil {
+			t.Error("fake cluster info should not have metadata")
+		}
+		if MatchSelector(info, labels.SelectorFromSet(labels.Set{"env": "prod"})) {
+			t.Error("cluster without labels should not match selector")
+		}
+		if !MatchSelector(info, nil) {
+			t.Error("nil selector should match everything")
+		}
+	})
+
+	t.Run("configmap", func(t *testing.T) {
+		cmlist := &v1.ConfigMapList{Items: []v1.ConfigMap{{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "cluster-a",
+				Labels:      map[string]string{"env": "prod", "region": "eu"},
+				Annotations: map[string]string{"owner": "team-a"},
+			},
+			Data: map[string]string{"kubeconfig": "data"},
+		}}}
+		list := configmap2ClusterCfgInfo(cmlist, "kubeconfig", "status")
+		if len(list) != 1 || GetClusterAnnotations(list[0])["owner"] != "team-a" {
+			t.Fatalf("expect cluster-a with annotations, but got %+v", list)
+		}
+		selector, _ := labels.Parse("env=prod,region=eu")
+		if !SelectorFilter(selector)(list[0]) {
+			t.Error("cluster-a should match selector")
+		}
+	})
+
+	t.Run("path sidecar file", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "metadata")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		ioutil.WriteFile(filepath.Join(dir, "cluster-a.yaml"), []byte("kubeconfig"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "cluster-a.yaml"+MetadataFileSuffix), []byte("labels:\n  env: prod\nannotations:\n  owner: team-a\n"), 0644)
+		ioutil.WriteFile(filepath.Join(dir, "cluster-b.yaml"), []byte("kubeconfig"), 0644)
+
+		cfg, err := NewClusterCfgManagerWithPath(dir, "", api.KubeConfigTypeRawString)
+		if err != nil {
+			t.Fatal(err)
+		}
+		list, err := cfg.GetAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 2 {
+			t.Fatalf("sidecar file should not be kubeconfig, but got %d clusters", len(list))
+		}
+		for _, info := range list {
+			switch info.GetName() {
+			case "cluster-a.yaml":
+				if GetClusterLabels(info)["env"] != "prod" || GetClusterAnnotations(info)["owner"] != "team-a" {
+					t.Errorf("cluster-a metadata unexpect %+v %+v", GetClusterLabels(info), GetClusterAnnotations(info))
+				}
+			case "cluster-b.yaml":
+				if len(GetClusterLabels(info)) != 0 {
+					t.Errorf("cluster-b should not have labels, but got %+v", GetClusterLabels(info))
+				}
+			default:
+				t.Errorf("unexpect cluster %s", info.GetName())
+			}
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package configuration
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+var defaultNotifyBufferSize = 100
+
+// ClusterCfgEventType cluster configuration change type
+type ClusterCfgEventType string
+
+// ClusterCfgAdded ClusterCfgUpdated ClusterCfgDeleted
+const (
+	ClusterCfgAdded   ClusterCfgEventType = "Added"
+	ClusterCfgUpdated ClusterCfgEventType = "Updated"
+	ClusterCfgDeleted ClusterCfgEventType = "Deleted"
+)
+
+// ClusterCfgEvent notification of cluster configuration changed,
+// Name is the source object name, the receiver should invoke GetAll to get latest configuration
+type ClusterCfgEvent struct {
+	Type ClusterCfgEventType
+	Name string
+}
+
+// ClusterCfgNotifier optional interface of api.ClusterConfigurationManager,
+// push changes instead of waiting the next GetAll polling
+type ClusterCfgNotifier interface {
+	// Notify returns a channel receives events until ctx cancelled, the channel closed after that
+	Notify(ctx context.Context) (<-chan ClusterCfgEvent, error)
+}
+
+// notifyWithInformer run informer and convert the object events to ClusterCfgEvent
+func notifyWithInformer(ctx context.Context, informer cache.SharedIndexInformer) (<-chan ClusterCfgEvent, error) {
+	ch := make(chan ClusterCfgEvent, defaultNotifyBufferSize)
+	send := func(eventType ClusterCfgEventType, obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			klog.Errorf("cluster configuration notify unknown object %T", obj)
+			return
+		}
+		select {
+		case ch <- ClusterCfgEvent{Type: eventType, Name: accessor.GetName()}:
+		case <-ctx.Done():
+		}
+	}
+
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			send(ClusterCfgAdded, obj)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldAccessor, oldErr := meta.Accessor(oldObj)
+			newAccessor, newErr := meta.Accessor(newObj)
+			if oldErr == nil && newErr == nil && oldAccessor.GetResourceVersion() == newAccessor.GetResourceVersion() {
+				// periodic resync
+				return
+			}
+			if onlyClusterStatusChanged(oldObj, newObj) {
+				// written by ClusterStatusReporter
+				return
+			}
+			send(ClusterCfgUpdated, newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			send(ClusterCfgDeleted, obj)
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("add cluster configuration notify handler failed %+v", err)
+	}
+
+	go func() {
+		informer.Run(ctx.Done())
+		close(ch)
+	}()
+	return ch, nil
+}
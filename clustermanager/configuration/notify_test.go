@@ -0,0 +1,88 @@
+package configuration
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/symcn/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func waitClusterCfgEvent(t *testing.T, ch <-chan ClusterCfgEvent, eventType ClusterCfgEventType, name string) {
+	timeout := time.After(time.Second * 5)
+	for {
+		select {
+		case event := <-ch:
+			if event.Type == eventType && event.Name == name {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("wait event %s %s timeout", eventType, name)
+		}
+	}
+}
+
+func TestConfigmapNotify(t *testing.T) {
+	kubeInterface := fake.NewSimpleClientset()
+	cfg := NewClusterCfgManagerWithCM(kubeInterface, "default", map[string]string{"k1": "v1"}, "kubeconfig", "status")
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ch, err := cfg.(ClusterCfgNotifier).Notify(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cluster-a", Labels: map[string]string{"k1": "v1"}},
+		Data:       map[string]string{"kubeconfig": "data"},
+	}
+	if _, err = kubeInterface.CoreV1().ConfigMaps("default").Create(ctx, cm, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitClusterCfgEvent(t, ch, ClusterCfgAdded, "cluster-a")
+
+	if err = kubeInterface.CoreV1().ConfigMaps("default").Delete(ctx, "cluster-a", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	waitClusterCfgEvent(t, ch, ClusterCfgDeleted, "cluster-a")
+
+	cancel()
+	for range ch {
+	}
+}
+
+func TestPathNotify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "notify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg, err := NewClusterCfgManagerWithPath(dir, ".yaml", api.KubeConfigTypeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ch, err := cfg.(ClusterCfgNotifier).Notify(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "ignore.txt"), []byte("data"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "cluster-a.yaml"), []byte("kubeconfig"), 0644)
+	waitClusterCfgEvent(t, ch, ClusterCfgAdded, "cluster-a.yaml")
+
+	ioutil.WriteFile(filepath.Join(dir, "cluster-a.yaml"+MetadataFileSuffix), []byte("labels: {}"), 0644)
+	waitClusterCfgEvent(t, ch, ClusterCfgAdded, "cluster-a.yaml")
+
+	os.Remove(filepath.Join(dir, "cluster-a.yaml"))
+	waitClusterCfgEvent(t, ch, ClusterCfgDeleted, "cluster-a.yaml")
+}
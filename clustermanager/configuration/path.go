@@ -1,48 +1,77 @@
 package configuration
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"text/template"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/symcn/api"
 	"k8s.io/klog/v2"
 )
 
+// secretVolumeDataDir the symlink of Kubernetes Secret and ConfigMap volume,
+// kubelet updates files by swapping it to a new timestamped directory
+const secretVolumeDataDir = "..data"
+
+// PathOptions options of path clusterconfiguration manager
+type PathOptions struct {
+	Dir            string
+	Suffix         string
+	KubeConfigType api.KubeConfigType
+	// Recursive find kubeconfig files with subdirectories
+	Recursive bool
+	// NameTemplate text/template of cluster name, default {{.Name}}, fields:
+	// .Name filename without suffix, .FileName filename, .Dir relative directory with "/" replaced by "-"
+	NameTemplate string
+	Filter       FilterHandler
+}
+
+// PathNameData fields of PathOptions.NameTemplate
+type PathNameData struct {
+	Name     string
+	FileName string
+	Dir      string
+}
+
 // cfgWithPath clusterconfiguration manager with file path
 type cfgWithPath struct {
 	dir            string
 	suffix         string
 	kubeConfigType api.KubeConfigType
 	filter         FilterHandler
+	recursive      bool
+	// legacyName use the filename with suffix as cluster name
+	legacyName   bool
+	nameTemplate *template.Template
 }
 
-// NewClusterCfgManagerWithPath build cfgWithPath
+// NewClusterCfgManagerWithPath build cfgWithPath, the cluster name is the filename with suffix
 func NewClusterCfgManagerWithPath(dir string, suffix string, kubeConfigType api.KubeConfigType) (api.ClusterConfigurationManager, error) {
-	s, err := os.Stat(dir)
-	if err != nil {
-		return nil, fmt.Errorf("NewClusterCfgManagerWithPath %s is not exist %+v", dir, err)
-	}
-	if !s.IsDir() {
-		return nil, fmt.Errorf("NewClusterCfgManagerWithPath %s is not directory", dir)
+	if err := checkDir(dir); err != nil {
+		return nil, err
 	}
 
 	return &cfgWithPath{
 		dir:            dir,
 		suffix:         suffix,
 		kubeConfigType: kubeConfigType,
+		legacyName:     true,
 	}, nil
 }
 
 // NewClusterCfgManagerWithPath build cfgWithPath
 func NewClusterCfgManagerWithPathWithFilter(dir string, suffix string, kubeConfigType api.KubeConfigType, filter FilterHandler) (api.ClusterConfigurationManager, error) {
-	s, err := os.Stat(dir)
-	if err != nil {
-		return nil, fmt.Errorf("NewClusterCfgManagerWithPath %s is not exist %+v", dir, err)
-	}
-	if !s.IsDir() {
-		return nil, fmt.Errorf("NewClusterCfgManagerWithPath %s is not directory", dir)
+	if err := checkDir(dir); err != nil {
+		return nil, err
 	}
 
 	return &cfgWithPath{
@@ -50,41 +79,287 @@ func NewClusterCfgManagerWithPathWithFilter(dir string, suffix string, kubeConfi
 		suffix:         suffix,
 		kubeConfigType: kubeConfigType,
 		filter:         filter,
+		legacyName:     true,
 	}, nil
 }
 
+// NewClusterCfgManagerWithPathOptions build cfgWithPath, the cluster name is the filename without suffix or NameTemplate
+func NewClusterCfgManagerWithPathOptions(opts *PathOptions) (api.ClusterConfigurationManager, error) {
+	if opts == nil {
+		return nil, errors.New("NewClusterCfgManagerWithPathOptions options is nil")
+	}
+	if err := checkDir(opts.Dir); err != nil {
+		return nil, err
+	}
+
+	cp := &cfgWithPath{
+		dir:            opts.Dir,
+		suffix:         opts.Suffix,
+		kubeConfigType: opts.KubeConfigType,
+		filter:         opts.Filter,
+		recursive:      opts.Recursive,
+	}
+	if opts.NameTemplate != "" {
+		tpl, err := template.New("name").Option("missingkey=error").Parse(opts.NameTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("NewClusterCfgManagerWithPathOptions parse name template failed %+v", err)
+		}
+		cp.nameTemplate = tpl
+	}
+	return cp, nil
+}
+
+func checkDir(dir string) error {
+	s, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("NewClusterCfgManagerWithPath %s is not exist %+v", dir, err)
+	}
+	if !s.IsDir() {
+		return fmt.Errorf("NewClusterCfgManagerWithPath %s is not directory", dir)
+	}
+	return nil
+}
+
 func (cp *cfgWithPath) GetAll() ([]api.ClusterCfgInfo, error) {
-	files, err := ioutil.ReadDir(cp.dir)
+	paths, err := cp.findFiles()
 	if err != nil {
 		return nil, fmt.Errorf("get clusterconfiguration with path failed, open %s err %+v", cp.dir, err)
 	}
 
-	list := make([]api.ClusterCfgInfo, 0, len(files))
-	for _, file := range files {
-		if !strings.HasSuffix(file.Name(), cp.suffix) {
+	list := make([]api.ClusterCfgInfo, 0, len(paths))
+	names := map[string]string{}
+	for _, path := range paths {
+		name, err := cp.clusterName(path)
+		if err != nil {
+			return nil, fmt.Errorf("get clusterconfiguration %s build name err %+v", path, err)
+		}
+		if exist, ok := names[name]; ok {
+			klog.Warningf("Get clusterconfiguration with path %s name %s conflict with %s, skip", path, name, exist)
 			continue
 		}
 
-		path := cp.dir + "/" + file.Name()
+		md, err := readMetadataFile(path + MetadataFileSuffix)
+		if err != nil {
+			return nil, fmt.Errorf("get clusterconfiguration %+v", err)
+		}
 
 		switch cp.kubeConfigType {
 
 		case api.KubeConfigTypeFile:
-			list = append(list, BuildClusterCfgInfo(file.Name(), cp.kubeConfigType, path, ""))
+			list = append(list, BuildClusterCfgInfoWithMetadata(name, cp.kubeConfigType, path, "", md.Labels, md.Annotations))
 
 		case api.KubeConfigTypeRawString:
 			data, err := ioutil.ReadFile(path)
 			if err != nil {
 				return nil, fmt.Errorf("get clusterconfiguration read %s err %+v", path, err)
 			}
-			list = append(list, BuildClusterCfgInfo(file.Name(), cp.kubeConfigType, string(data), ""))
+			list = append(list, BuildClusterCfgInfoWithMetadata(name, cp.kubeConfigType, string(data), "", md.Labels, md.Annotations))
 
 		default:
 			klog.Warningf("Get clusterconfiguration with path not support type %s", cp.kubeConfigType)
+			continue
 		}
+		names[name] = path
 	}
 
 	list = filterClusterInfo(list, cp.filter)
 
 	return list, nil
 }
+
+// findFiles returns the kubeconfig files sorted by path, symlinks are followed,
+// the ..data and timestamped directories of Secret volume are skipped.
+func (cp *cfgWithPath) findFiles() ([]string, error) {
+	paths := []string{}
+	err := walkDir(cp.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == cp.dir {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), "..") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if !cp.recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !cp.isKubeconfigFile(d.Name()) {
+			return nil
+		}
+
+		// Secret volume file is symlink to ..data/<file>
+		s, err := os.Stat(path)
+		if err != nil {
+			klog.Warningf("Get clusterconfiguration with path stat %s err %+v", path, err)
+			return nil
+		}
+		if s.IsDir() {
+			return nil
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	sort.Strings(paths)
+	return paths, err
+}
+
+// walkDir filepath.WalkDir with the root resolved when it is a symlink, such as ConfigMap volume mounted by symlink,
+// fn receives the path under dir, so the cluster name and kubeconfig path not changed with the symlink target
+func walkDir(dir string, fn fs.WalkDirFunc) error {
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if rel, relErr := filepath.Rel(root, path); relErr == nil {
+			if rel == "." {
+				path = dir
+			} else {
+				path = filepath.Join(dir, rel)
+			}
+		}
+		return fn(path, d, err)
+	})
+}
+
+func (cp *cfgWithPath) isKubeconfigFile(fileName string) bool {
+	return strings.HasSuffix(fileName, cp.suffix) && !strings.HasSuffix(fileName, MetadataFileSuffix)
+}
+
+// clusterName build cluster name with path
+func (cp *cfgWithPath) clusterName(path string) (string, error) {
+	fileName := filepath.Base(path)
+	if cp.legacyName {
+		return fileName, nil
+	}
+
+	data := PathNameData{
+		Name:     strings.TrimSuffix(fileName, cp.suffix),
+		FileName: fileName,
+	}
+	if rel, err := filepath.Rel(cp.dir, filepath.Dir(path)); err == nil && rel != "." {
+		data.Dir = strings.ReplaceAll(filepath.ToSlash(rel), "/", "-")
+	}
+	if cp.nameTemplate == nil {
+		return data.Name, nil
+	}
+
+	buf := &bytes.Buffer{}
+	if err := cp.nameTemplate.Execute(buf, data); err != nil {
+		return "", err
+	}
+	if buf.Len() == 0 {
+		return "", errors.New("name is empty")
+	}
+	return buf.String(), nil
+}
+
+// Notify implements ClusterCfgNotifier, watch the files of directory and subdirectories with Recursive
+func (cp *cfgWithPath) Notify(ctx context.Context) (<-chan ClusterCfgEvent, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("build file watcher failed %+v", err)
+	}
+	if err = cp.watchDir(watcher, cp.dir); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	ch := make(chan ClusterCfgEvent, defaultNotifyBufferSize)
+	go func() {
+		defer close(ch)
+		defer watcher.Close()
+
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				clsEvent, ok := cp.convertEvent(watcher, event)
+				if !ok {
+					continue
+				}
+				select {
+				case ch <- clsEvent:
+				case <-ctx.Done():
+					return
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				klog.Errorf("watch %s failed %+v", cp.dir, err)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// watchDir add dir to watcher, subdirectories also added with Recursive
+func (cp *cfgWithPath) watchDir(watcher *fsnotify.Watcher, dir string) error {
+	return walkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != dir && (!cp.recursive || strings.HasPrefix(d.Name(), "..")) {
+			return filepath.SkipDir
+		}
+		if err = watcher.Add(path); err != nil {
+			return fmt.Errorf("watch %s failed %+v", path, err)
+		}
+		return nil
+	})
+}
+
+// convertEvent convert file event to ClusterCfgEvent, returns false when should be ignored
+func (cp *cfgWithPath) convertEvent(watcher *fsnotify.Watcher, event fsnotify.Event) (ClusterCfgEvent, bool) {
+	fileName := filepath.Base(event.Name)
+	if fileName == secretVolumeDataDir && event.Has(fsnotify.Create) {
+		// Secret volume symlink swapped, all files may be modified
+		return ClusterCfgEvent{Type: ClusterCfgUpdated}, true
+	}
+	if strings.HasPrefix(fileName, "..") {
+		return ClusterCfgEvent{}, false
+	}
+
+	if cp.recursive && event.Has(fsnotify.Create) {
+		if s, err := os.Stat(event.Name); err == nil && s.IsDir() {
+			if err = cp.watchDir(watcher, event.Name); err != nil {
+				klog.Errorf("watch new directory %s failed %+v", event.Name, err)
+			}
+			return ClusterCfgEvent{Type: ClusterCfgAdded}, true
+		}
+	}
+
+	path := strings.TrimSuffix(event.Name, MetadataFileSuffix)
+	if !strings.HasSuffix(filepath.Base(path), cp.suffix) {
+		return ClusterCfgEvent{}, false
+	}
+	name, err := cp.clusterName(path)
+	if err != nil {
+		name = filepath.Base(path)
+	}
+
+	switch {
+	case event.Has(fsnotify.Create):
+		return ClusterCfgEvent{Type: ClusterCfgAdded, Name: name}, true
+	case event.Has(fsnotify.Write):
+		return ClusterCfgEvent{Type: ClusterCfgUpdated, Name: name}, true
+	case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+		return ClusterCfgEvent{Type: ClusterCfgDeleted, Name: name}, true
+	}
+	return ClusterCfgEvent{}, false
+}
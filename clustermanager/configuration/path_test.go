@@ -1,7 +1,11 @@
 package configuration
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/symcn/api"
@@ -159,3 +163,126 @@ func buildTmpWithMockKubeConfig(num int) {
 	// }
 	// clientcmd.WriteToFile(*cfg, mockKubeConfigPath)
 }
+
+func TestNewClusterCfgManagerWithPathOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pathoptions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Secret volume layout: cluster-a.yaml -> ..data/cluster-a.yaml, ..data -> ..2023_01_01
+	os.MkdirAll(filepath.Join(dir, "..2023_01_01"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "..2023_01_01", "cluster-a.yaml"), []byte("kubeconfig-a"), 0644)
+	os.Symlink("..2023_01_01", filepath.Join(dir, "..data"))
+	os.Symlink(filepath.Join("..data", "cluster-a.yaml"), filepath.Join(dir, "cluster-a.yaml"))
+	// recursive layout
+	os.MkdirAll(filepath.Join(dir, "eu", "prod"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "eu", "prod", "cluster-b.yaml"), []byte("kubeconfig-b"), 0644)
+
+	getNames := func(opts *PathOptions) []string {
+		cfg, err := NewClusterCfgManagerWithPathOptions(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		list, err := cfg.GetAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for _, info := range list {
+			names = append(names, info.GetName()+"="+info.GetKubeConfig())
+		}
+		return names
+	}
+
+	names := getNames(&PathOptions{Dir: dir, Suffix: ".yaml", KubeConfigType: api.KubeConfigTypeRawString})
+	if len(names) != 1 || names[0] != "cluster-a=kubeconfig-a" {
+		t.Errorf("expect cluster-a without suffix, but got %v", names)
+	}
+
+	names = getNames(&PathOptions{Dir: dir, Suffix: ".yaml", KubeConfigType: api.KubeConfigTypeRawString, Recursive: true, NameTemplate: "{{if .Dir}}{{.Dir}}-{{end}}{{.Name}}"})
+	if len(names) != 2 || names[0] != "cluster-a=kubeconfig-a" || names[1] != "eu-prod-cluster-b=kubeconfig-b" {
+		t.Errorf("expect cluster-a and eu-prod-cluster-b, but got %v", names)
+	}
+
+	if _, err = NewClusterCfgManagerWithPathOptions(&PathOptions{Dir: dir, NameTemplate: "{{.Name"}); err == nil {
+		t.Error("invalid template should be error")
+	}
+
+	t.Run("notify symlink swap", func(t *testing.T) {
+		cfg, _ := NewClusterCfgManagerWithPathOptions(&PathOptions{Dir: dir, Suffix: ".yaml", KubeConfigType: api.KubeConfigTypeRawString, Recursive: true})
+		ctx, cancel := context.WithCancel(context.TODO())
+		defer cancel()
+		ch, err := cfg.(ClusterCfgNotifier).Notify(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// kubelet swap ..data to the new timestamped directory
+		os.MkdirAll(filepath.Join(dir, "..2023_01_02"), 0755)
+		ioutil.WriteFile(filepath.Join(dir, "..2023_01_02", "cluster-a.yaml"), []byte("kubeconfig-a2"), 0644)
+		os.Symlink("..2023_01_02", filepath.Join(dir, "..data_tmp"))
+		os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data"))
+		waitClusterCfgEvent(t, ch, ClusterCfgUpdated, "")
+
+		// new file in subdirectory
+		ioutil.WriteFile(filepath.Join(dir, "eu", "prod", "cluster-c.yaml"), []byte("kubeconfig-c"), 0644)
+		waitClusterCfgEvent(t, ch, ClusterCfgAdded, "cluster-c")
+
+		names := getNames(&PathOptions{Dir: dir, Suffix: ".yaml", KubeConfigType: api.KubeConfigTypeRawString})
+		if len(names) != 1 || names[0] != "cluster-a=kubeconfig-a2" {
+			t.Errorf("expect cluster-a updated, but got %v", names)
+		}
+	})
+}
+
+func TestNewClusterCfgManagerWithSymlinkPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "symlinkpath")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the directory mounted by symlink, such as ConfigMap volume
+	target := filepath.Join(dir, "target")
+	os.MkdirAll(filepath.Join(target, "eu"), 0755)
+	ioutil.WriteFile(filepath.Join(target, "cluster-a.yaml"), []byte("kubeconfig-a"), 0644)
+	ioutil.WriteFile(filepath.Join(target, "eu", "cluster-b.yaml"), []byte("kubeconfig-b"), 0644)
+	link := filepath.Join(dir, "link")
+	if err = os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := NewClusterCfgManagerWithPath(link, ".yaml", api.KubeConfigTypeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := cfg.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].GetName() != "cluster-a.yaml" || list[0].GetKubeConfig() != filepath.Join(link, "cluster-a.yaml") {
+		t.Fatalf("expect cluster-a.yaml under symlink directory, but got %+v", list)
+	}
+
+	cfg, err = NewClusterCfgManagerWithPathOptions(&PathOptions{Dir: link, Suffix: ".yaml", KubeConfigType: api.KubeConfigTypeRawString, Recursive: true, NameTemplate: "{{if .Dir}}{{.Dir}}-{{end}}{{.Name}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list, err = cfg.GetAll(); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].GetName() != "cluster-a" || list[1].GetName() != "eu-cluster-b" {
+		t.Fatalf("expect cluster-a and eu-cluster-b with recursive, but got %+v", list)
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	ch, err := cfg.(ClusterCfgNotifier).Notify(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(filepath.Join(target, "eu", "cluster-c.yaml"), []byte("kubeconfig-c"), 0644)
+	waitClusterCfgEvent(t, ch, ClusterCfgAdded, "eu-cluster-c")
+}
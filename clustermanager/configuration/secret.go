@@ -0,0 +1,132 @@
+package configuration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/symcn/api"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+var (
+	listSecretTimeout = time.Second * 5
+)
+
+// SecretTypeKubeconfig kubeconfig typed secret, read SecretKubeconfigKey when the dataKey not exist
+const (
+	SecretTypeKubeconfig v1.SecretType = "symcn.io/kubeconfig"
+	SecretKubeconfigKey                = "kubeconfig"
+)
+
+// cfgWithSecret clusterconfiguration manager with kubernetes secret,
+// just Opaque and SecretTypeKubeconfig secrets are used
+type cfgWithSecret struct {
+	kubeInterface kubernetes.Interface
+	namespace     string
+	label         map[string]string
+	dataKey       string
+	statusKey     string
+	filter        FilterHandler
+}
+
+// NewClusterCfgManagerWithSecret build cfgWithSecret
+func NewClusterCfgManagerWithSecret(kubeInterface kubernetes.Interface, namespace string, label map[string]string, dataKey, statusKey string) api.ClusterConfigurationManager {
+	return &cfgWithSecret{
+		kubeInterface: kubeInterface,
+		namespace:     namespace,
+		label:         label,
+		dataKey:       dataKey,
+		statusKey:     statusKey,
+	}
+}
+
+// NewClusterCfgManagerWithSecretWithFilter build cfgWithSecret with filter
+func NewClusterCfgManagerWithSecretWithFilter(kubeInterface kubernetes.Interface, namespace string, label map[string]string, dataKey, statusKey string, filter FilterHandler) api.ClusterConfigurationManager {
+	return &cfgWithSecret{
+		kubeInterface: kubeInterface,
+		namespace:     namespace,
+		label:         label,
+		dataKey:       dataKey,
+		statusKey:     statusKey,
+		filter:        filter,
+	}
+}
+
+func (cs *cfgWithSecret) GetAll() ([]api.ClusterCfgInfo, error) {
+	ctx, cancel := context.WithTimeout(context.TODO(), listSecretTimeout)
+	defer cancel()
+
+	secretList, err := cs.kubeInterface.CoreV1().Secrets(cs.namespace).List(ctx, metav1.ListOptions{LabelSelector: buildLabelSelector(cs.label)})
+	if err != nil {
+		return nil, fmt.Errorf("get clusterconfiguration with secret failed namespace:%s label:%+v err:%+v", cs.namespace, cs.label, err)
+	}
+
+	list := secret2ClusterCfgInfo(secretList, cs.dataKey, cs.statusKey)
+	list = filterClusterInfo(list, cs.filter)
+	return list, nil
+}
+
+// Notify implements ClusterCfgNotifier, watch the secrets with namespace and label
+func (cs *cfgWithSecret) Notify(ctx context.Context) (<-chan ClusterCfgEvent, error) {
+	informer := coreinformers.NewFilteredSecretInformer(cs.kubeInterface, cs.namespace, 0, cache.Indexers{}, func(opts *metav1.ListOptions) {
+		opts.LabelSelector = buildLabelSelector(cs.label)
+	})
+	return notifyWithInformer(ctx, informer)
+}
+
+// ReportClusterStatus implements ClusterStatusReporter, patch labels and annotations of the secret
+func (cs *cfgWithSecret) ReportClusterStatus(ctx context.Context, clusterInfo api.ClusterCfgInfo, status ClusterStatus) error {
+	patch, err := statusMergePatch(status)
+	if err != nil {
+		return err
+	}
+	_, err = cs.kubeInterface.CoreV1().Secrets(cs.namespace).Patch(ctx, GetClusterOriginalName(clusterInfo), types.MergePatchType, patch, metav1.PatchOptions{})
+	if apierrors.IsNotFound(err) {
+		// removed with the cluster, nothing to report
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("report cluster %s status to secret failed %+v", clusterInfo.GetName(), err)
+	}
+	return nil
+}
+
+// secret2ClusterCfgInfo secretlist to clusterconfiguration info
+func secret2ClusterCfgInfo(secretList *v1.SecretList, dataKey, statusKey string) []api.ClusterCfgInfo {
+	list := make([]api.ClusterCfgInfo, 0, len(secretList.Items))
+
+	for _, secret := range secretList.Items {
+		kubecfg, ok := secret.Data[dataKey]
+		switch secret.Type {
+		case v1.SecretTypeOpaque, "":
+		case SecretTypeKubeconfig:
+			if !ok {
+				kubecfg, ok = secret.Data[SecretKubeconfigKey]
+			}
+		default:
+			// such as service account token
+			continue
+		}
+		if !ok {
+			// if not exist dataKey continue
+			continue
+		}
+		if status, ok := secret.Data[statusKey]; ok && !strings.EqualFold(string(status), "true") {
+			// if status not exist means should connected
+			// status is equal true means should connected
+			// otherwise disconnected
+			continue
+		}
+		list = append(list, BuildClusterCfgInfoWithMetadata(secret.Name, api.KubeConfigTypeRawString, string(kubecfg), "", withoutClusterStatus(secret.Labels), withoutClusterStatus(secret.Annotations)))
+	}
+
+	return list
+}
@@ -0,0 +1,73 @@
+package configuration
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNewClusterCfgManagerWithSecret(t *testing.T) {
+	kubeInterface := fake.NewSimpleClientset(
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cluster-a", Labels: map[string]string{"k1": "v1"}},
+			Type:       v1.SecretTypeOpaque,
+			Data:       map[string][]byte{"data": []byte("data-a")},
+		},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cluster-b"},
+			Type:       v1.SecretTypeOpaque,
+			Data:       map[string][]byte{"data": []byte("data-b")},
+		},
+	)
+
+	cfg := NewClusterCfgManagerWithSecret(kubeInterface, "default", map[string]string{"k1": "v1"}, "data", "status")
+	list, err := cfg.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].GetName() != "cluster-a" || list[0].GetKubeConfig() != "data-a" {
+		t.Errorf("expect cluster-a selected with label, but got %+v", list)
+	}
+}
+
+func TestSecret2ClusterCfgInfo(t *testing.T) {
+	dataKey := "kubeconfig.yaml"
+	statusKey := "status"
+
+	secretList := &v1.SecretList{
+		Items: []v1.Secret{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "opaque"},
+				Type:       v1.SecretTypeOpaque,
+				Data:       map[string][]byte{dataKey: []byte("data1")},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "disconnected"},
+				Type:       v1.SecretTypeOpaque,
+				Data:       map[string][]byte{dataKey: []byte("data2"), statusKey: []byte("false")},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "kubeconfig-typed"},
+				Type:       SecretTypeKubeconfig,
+				Data:       map[string][]byte{SecretKubeconfigKey: []byte("data3")},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "opaque-without-key"},
+				Type:       v1.SecretTypeOpaque,
+				Data:       map[string][]byte{SecretKubeconfigKey: []byte("data4")},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "token"},
+				Type:       v1.SecretTypeServiceAccountToken,
+				Data:       map[string][]byte{dataKey: []byte("data5")},
+			},
+		},
+	}
+
+	list := secret2ClusterCfgInfo(secretList, dataKey, statusKey)
+	if len(list) != 2 || list[0].GetName() != "opaque" || list[1].GetName() != "kubeconfig-typed" || list[1].GetKubeConfig() != "data3" {
+		t.Errorf("expect opaque and kubeconfig-typed, but got %+v", list)
+	}
+}
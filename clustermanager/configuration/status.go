@@ -0,0 +1,113 @@
+package configuration
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/symcn/api"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ClusterStatusPrefix the labels and annotations with prefix are written by multiclient,
+// they are not cluster metadata and ignored by cluster configuration notify
+const ClusterStatusPrefix = "status.symcn.io/"
+
+// LabelClusterConnected "true" or "false", such as kubectl get cm -l status.symcn.io/connected=false
+// LabelClusterSynced "true" or "false"
+// AnnotationClusterLastHealthCheck RFC3339 time of the last health check
+// AnnotationClusterServerVersion git version of the cluster apiserver
+// AnnotationClusterLastError the last error of the cluster, removed when no error
+const (
+	LabelClusterConnected            = ClusterStatusPrefix + "connected"
+	LabelClusterSynced               = ClusterStatusPrefix + "synced"
+	AnnotationClusterLastHealthCheck = ClusterStatusPrefix + "last-health-check"
+	AnnotationClusterServerVersion   = ClusterStatusPrefix + "server-version"
+	AnnotationClusterLastError       = ClusterStatusPrefix + "last-error"
+)
+
+// ClusterStatus the observed state of cluster
+type ClusterStatus struct {
+	Connected       bool
+	Synced          bool
+	LastHealthCheck time.Time
+	ServerVersion   string
+	LastError       string
+}
+
+// ClusterStatusReporter optional interface of api.ClusterConfigurationManager,
+// write the observed state back to the source object of cluster
+type ClusterStatusReporter interface {
+	ReportClusterStatus(ctx context.Context, clusterInfo api.ClusterCfgInfo, status ClusterStatus) error
+}
+
+// statusMergePatch build merge patch of labels and annotations, empty values are removed
+func statusMergePatch(status ClusterStatus) ([]byte, error) {
+	annotations := map[string]interface{}{
+		AnnotationClusterLastHealthCheck: nil,
+		AnnotationClusterServerVersion:   nil,
+		AnnotationClusterLastError:       nil,
+	}
+	if !status.LastHealthCheck.IsZero() {
+		annotations[AnnotationClusterLastHealthCheck] = status.LastHealthCheck.UTC().Format(time.RFC3339)
+	}
+	if status.ServerVersion != "" {
+		annotations[AnnotationClusterServerVersion] = status.ServerVersion
+	}
+	if status.LastError != "" {
+		annotations[AnnotationClusterLastError] = status.LastError
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{
+				LabelClusterConnected: strconv.FormatBool(status.Connected),
+				LabelClusterSynced:    strconv.FormatBool(status.Synced),
+			},
+			"annotations": annotations,
+		},
+	})
+}
+
+// withoutClusterStatus returns a copy without the keys with ClusterStatusPrefix
+func withoutClusterStatus(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	result := make(map[string]string, len(m))
+	for k, v := range m {
+		if !strings.HasPrefix(k, ClusterStatusPrefix) {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// onlyClusterStatusChanged returns true if the objects are equal except status labels and annotations
+func onlyClusterStatusChanged(oldObj, newObj interface{}) bool {
+	oldRuntimeObj, ok := oldObj.(runtime.Object)
+	if !ok {
+		return false
+	}
+	newRuntimeObj, ok := newObj.(runtime.Object)
+	if !ok {
+		return false
+	}
+
+	oldCopy, newCopy := oldRuntimeObj.DeepCopyObject(), newRuntimeObj.DeepCopyObject()
+	for _, obj := range []runtime.Object{oldCopy, newCopy} {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return false
+		}
+		accessor.SetResourceVersion("")
+		accessor.SetManagedFields(nil)
+		accessor.SetLabels(withoutClusterStatus(accessor.GetLabels()))
+		accessor.SetAnnotations(withoutClusterStatus(accessor.GetAnnotations()))
+	}
+	return apiequality.Semantic.DeepEqual(oldCopy, newCopy)
+}
@@ -0,0 +1,75 @@
+package configuration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/symcn/api"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestReportClusterStatusWithCM(t *testing.T) {
+	kubeInterface := fake.NewSimpleClientset(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cluster-a", Labels: map[string]string{"env": "prod"}},
+		Data:       map[string]string{"kubeconfig": "data-a"},
+	})
+	cfg := NewClusterCfgManagerWithCM(kubeInterface, "default", nil, "kubeconfig", "status")
+	reporter, ok := cfg.(ClusterStatusReporter)
+	if !ok {
+		t.Fatal("configmap manager should implement ClusterStatusReporter")
+	}
+
+	getConfigMap := func() *v1.ConfigMap {
+		cm, err := kubeInterface.CoreV1().ConfigMaps("default").Get(context.TODO(), "cluster-a", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cm
+	}
+	info := BuildClusterCfgInfo("cluster-a", api.KubeConfigTypeRawString, "data-a", "")
+
+	err := reporter.ReportClusterStatus(context.TODO(), info, ClusterStatus{LastHealthCheck: time.Now(), LastError: "timeout"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := getConfigMap()
+	if old.Labels[LabelClusterConnected] != "false" || old.Labels["env"] != "prod" || old.Annotations[AnnotationClusterLastError] != "timeout" {
+		t.Errorf("expect disconnected status, but got %+v %+v", old.Labels, old.Annotations)
+	}
+
+	err = reporter.ReportClusterStatus(context.TODO(), info, ClusterStatus{Connected: true, Synced: true, LastHealthCheck: time.Now(), ServerVersion: "v1.26.4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm := getConfigMap()
+	if cm.Labels[LabelClusterConnected] != "true" || cm.Labels[LabelClusterSynced] != "true" || cm.Annotations[AnnotationClusterServerVersion] != "v1.26.4" {
+		t.Errorf("expect connected status, but got %+v %+v", cm.Labels, cm.Annotations)
+	}
+	if _, ok := cm.Annotations[AnnotationClusterLastError]; ok {
+		t.Error("last error should be removed")
+	}
+	if !onlyClusterStatusChanged(old, cm) {
+		t.Error("status changed should be ignored by notify")
+	}
+	cm.Data["kubeconfig"] = "data-b"
+	if onlyClusterStatusChanged(old, cm) {
+		t.Error("data changed should be notified")
+	}
+
+	list, err := cfg.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || len(GetClusterLabels(list[0])) != 1 {
+		t.Errorf("status labels should not be cluster labels, but got %+v", GetClusterLabels(list[0]))
+	}
+
+	// removed configmap ignored
+	err = reporter.ReportClusterStatus(context.TODO(), BuildClusterCfgInfo("cluster-b", api.KubeConfigTypeRawString, "data-b", ""), ClusterStatus{})
+	if err != nil {
+		t.Errorf("report removed cluster should ignore not found, but got %+v", err)
+	}
+}
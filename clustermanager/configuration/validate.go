@@ -0,0 +1,292 @@
+package configuration
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/symcn/api"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/util/cert"
+)
+
+var (
+	// defaultCertExpiringThreshold warning when the certificate expires within it
+	defaultCertExpiringThreshold = time.Hour * 24 * 30
+	// unregisteredAuthProviders the auth provider names probed not registered
+	unregisteredAuthProviders sync.Map
+)
+
+// DiagnosticSeverity severity of diagnostic
+type DiagnosticSeverity string
+
+// DiagnosticError the cluster can't be connected
+// DiagnosticWarning the cluster may be connected, but should be fixed
+const (
+	DiagnosticError   DiagnosticSeverity = "Error"
+	DiagnosticWarning DiagnosticSeverity = "Warning"
+)
+
+// Diagnostic codes
+const (
+	CodeUnsupportedKubeConfigType = "UnsupportedKubeConfigType"
+	CodeParseFailed               = "ParseFailed"
+	CodeContextNotFound           = "ContextNotFound"
+	CodeClusterNotFound           = "ClusterNotFound"
+	CodeAuthInfoNotFound          = "AuthInfoNotFound"
+	CodeServerEmpty               = "ServerEmpty"
+	CodeInsecureSkipTLSVerify     = "InsecureSkipTLSVerify"
+	CodeInvalidCA                 = "InvalidCA"
+	CodeInvalidClientCertificate  = "InvalidClientCertificate"
+	CodeCertificateExpired        = "CertificateExpired"
+	CodeCertificateNotYetValid    = "CertificateNotYetValid"
+	CodeCertificateExpiring       = "CertificateExpiring"
+	CodeUnsupportedAuthProvider   = "UnsupportedAuthProvider"
+	CodeExecPlugin                = "ExecPlugin"
+	CodeExecCommandNotFound       = "ExecCommandNotFound"
+	CodeNoCredential              = "NoCredential"
+)
+
+// Diagnostic one problem of cluster configuration
+type Diagnostic struct {
+	Severity DiagnosticSeverity
+	Code     string
+	Message  string
+}
+
+// ValidationResult diagnostics of one cluster
+type ValidationResult struct {
+	Name        string
+	Diagnostics []Diagnostic
+}
+
+// Valid returns true without error diagnostic
+func (r ValidationResult) Valid() bool {
+	for _, d := range r.Diagnostics {
+		if d.Severity == DiagnosticError {
+			return false
+		}
+	}
+	return true
+}
+
+// Err returns error joined with all error diagnostics, nil when valid
+func (r ValidationResult) Err() error {
+	msgs := []string{}
+	for _, d := range r.Diagnostics {
+		if d.Severity == DiagnosticError {
+			msgs = append(msgs, fmt.Sprintf("%s: %s", d.Code, d.Message))
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("cluster %s configuration invalid, %s", r.Name, strings.Join(msgs, "; "))
+}
+
+func (r *ValidationResult) add(severity DiagnosticSeverity, code, format string, args ...interface{}) {
+	r.Diagnostics = append(r.Diagnostics, Diagnostic{Severity: severity, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// ValidateClusterCfgManager validate all clusters of the manager
+func ValidateClusterCfgManager(manager api.ClusterConfigurationManager) ([]ValidationResult, error) {
+	list, err := manager.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ValidationResult, 0, len(list))
+	for _, info := range list {
+		results = append(results, ValidateClusterCfgInfo(info))
+	}
+	return results, nil
+}
+
+// ValidateClusterCfgInfo parse the kubeconfig and check the context, certificates and credential,
+// in-cluster configuration is not checked
+func ValidateClusterCfgInfo(info api.ClusterCfgInfo) ValidationResult {
+	result := ValidationResult{Name: info.GetName()}
+
+	var (
+		kubeconfig *clientcmdapi.Config
+		err        error
+	)
+	switch info.GetKubeConfigType() {
+	case api.KubeConfigTypeRawString:
+		kubeconfig, err = clientcmd.Load([]byte(info.GetKubeConfig()))
+	case api.KubeConfigTypeFile:
+		kubeconfig, err = loadKubeconfig(info)
+	case api.KubeConfigTypeInCluster:
+		return result
+	default:
+		result.add(DiagnosticError, CodeUnsupportedKubeConfigType, "kubeconfig type %s not supported", info.GetKubeConfigType())
+		return result
+	}
+	if err != nil {
+		result.add(DiagnosticError, CodeParseFailed, "parse kubeconfig failed %+v", err)
+		return result
+	}
+
+	contextName := info.GetKubeContext()
+	if contextName == "" {
+		contextName = kubeconfig.CurrentContext
+	}
+	kubeContext, ok := kubeconfig.Contexts[contextName]
+	if !ok || kubeContext == nil {
+		result.add(DiagnosticError, CodeContextNotFound, "context %q not found", contextName)
+		return result
+	}
+
+	if cluster, ok := kubeconfig.Clusters[kubeContext.Cluster]; !ok || cluster == nil {
+		result.add(DiagnosticError, CodeClusterNotFound, "cluster %q of context %q not found", kubeContext.Cluster, contextName)
+	} else {
+		validateCluster(&result, cluster)
+	}
+
+	if authInfo, ok := kubeconfig.AuthInfos[kubeContext.AuthInfo]; !ok || authInfo == nil {
+		result.add(DiagnosticError, CodeAuthInfoNotFound, "user %q of context %q not found", kubeContext.AuthInfo, contextName)
+	} else {
+		validateAuthInfo(&result, authInfo)
+	}
+	return result
+}
+
+func validateCluster(result *ValidationResult, cluster *clientcmdapi.Cluster) {
+	if cluster.Server == "" {
+		result.add(DiagnosticError, CodeServerEmpty, "server is empty")
+	}
+	if cluster.InsecureSkipTLSVerify {
+		result.add(DiagnosticWarning, CodeInsecureSkipTLSVerify, "server certificate not verified")
+	}
+
+	caData := cluster.CertificateAuthorityData
+	if len(caData) == 0 && cluster.CertificateAuthority != "" {
+		data, err := os.ReadFile(cluster.CertificateAuthority)
+		if err != nil {
+			result.add(DiagnosticError, CodeInvalidCA, "read certificate authority failed %+v", err)
+			return
+		}
+		caData = data
+	}
+	if len(caData) == 0 {
+		return
+	}
+
+	certs, err := cert.ParseCertsPEM(caData)
+	if err != nil {
+		result.add(DiagnosticError, CodeInvalidCA, "parse certificate authority failed %+v", err)
+		return
+	}
+	// the bundle works with any valid certificate, such as rotating the certificate authority
+	severity := DiagnosticError
+	now := time.Now()
+	for _, c := range certs {
+		if !now.Before(c.NotBefore) && !now.After(c.NotAfter) {
+			severity = DiagnosticWarning
+			break
+		}
+	}
+	for _, c := range certs {
+		validateCertificateTime(result, severity, "certificate authority", c)
+	}
+}
+
+func validateAuthInfo(result *ValidationResult, authInfo *clientcmdapi.AuthInfo) {
+	certData, keyData := authInfo.ClientCertificateData, authInfo.ClientKeyData
+	readFailed := false
+	if len(certData) == 0 && authInfo.ClientCertificate != "" {
+		data, err := os.ReadFile(authInfo.ClientCertificate)
+		if err != nil {
+			readFailed = true
+			result.add(DiagnosticError, CodeInvalidClientCertificate, "read client certificate failed %+v", err)
+		}
+		certData = data
+	}
+	if len(keyData) == 0 && authInfo.ClientKey != "" {
+		data, err := os.ReadFile(authInfo.ClientKey)
+		if err != nil {
+			readFailed = true
+			result.add(DiagnosticError, CodeInvalidClientCertificate, "read client key failed %+v", err)
+		}
+		keyData = data
+	}
+	hasCert := len(certData) > 0 || len(keyData) > 0 || readFailed
+	if hasCert && !readFailed {
+		validateClientCertificate(result, certData, keyData)
+	}
+
+	if authInfo.AuthProvider != nil && !authProviderRegistered(authInfo.AuthProvider.Name) {
+		// the plugin registered by importing, such as k8s.io/client-go/plugin/pkg/client/auth/oidc
+		result.add(DiagnosticError, CodeUnsupportedAuthProvider, "auth provider %s not registered", authInfo.AuthProvider.Name)
+	}
+	if authInfo.Exec != nil {
+		if _, err := exec.LookPath(authInfo.Exec.Command); err != nil {
+			result.add(DiagnosticError, CodeExecCommandNotFound, "exec plugin command %s not found %+v", authInfo.Exec.Command, err)
+		} else {
+			result.add(DiagnosticWarning, CodeExecPlugin, "credential provided by exec plugin %s", authInfo.Exec.Command)
+		}
+	}
+
+	if !hasCert && authInfo.Token == "" && authInfo.TokenFile == "" && authInfo.Username == "" &&
+		authInfo.AuthProvider == nil && authInfo.Exec == nil {
+		result.add(DiagnosticWarning, CodeNoCredential, "no credential, request as anonymous")
+	}
+}
+
+// authProviderRegistered check the plugin registered without building it. client-go not exposes the registry,
+// so probe by registering a factory which fails the same as not registered, and remember the probed names.
+func authProviderRegistered(name string) bool {
+	if _, probed := unregisteredAuthProviders.Load(name); probed {
+		return false
+	}
+	err := rest.RegisterAuthProviderPlugin(name, func(string, map[string]string, rest.AuthProviderConfigPersister) (rest.AuthProvider, error) {
+		return nil, fmt.Errorf("no Auth Provider found for name %q", name)
+	})
+	if err != nil {
+		// registered already
+		return true
+	}
+	unregisteredAuthProviders.Store(name, struct{}{})
+	return false
+}
+
+func validateClientCertificate(result *ValidationResult, certData, keyData []byte) {
+	if len(certData) == 0 || len(keyData) == 0 {
+		result.add(DiagnosticError, CodeInvalidClientCertificate, "client certificate and key must be set together")
+		return
+	}
+	pair, err := tls.X509KeyPair(certData, keyData)
+	if err != nil {
+		result.add(DiagnosticError, CodeInvalidClientCertificate, "load client certificate failed %+v", err)
+		return
+	}
+	if len(pair.Certificate) == 0 {
+		return
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		result.add(DiagnosticError, CodeInvalidClientCertificate, "parse client certificate failed %+v", err)
+		return
+	}
+	validateCertificateTime(result, DiagnosticError, "client certificate", leaf)
+}
+
+// validateCertificateTime add invalidSeverity diagnostic when expired or not yet valid
+func validateCertificateTime(result *ValidationResult, invalidSeverity DiagnosticSeverity, kind string, c *x509.Certificate) {
+	now := time.Now()
+	switch {
+	case now.After(c.NotAfter):
+		result.add(invalidSeverity, CodeCertificateExpired, "%s %s expired at %s", kind, c.Subject.CommonName, c.NotAfter.Format(time.RFC3339))
+	case now.Before(c.NotBefore):
+		result.add(invalidSeverity, CodeCertificateNotYetValid, "%s %s not valid before %s", kind, c.Subject.CommonName, c.NotBefore.Format(time.RFC3339))
+	case now.Add(defaultCertExpiringThreshold).After(c.NotAfter):
+		result.add(DiagnosticWarning, CodeCertificateExpiring, "%s %s expires at %s", kind, c.Subject.CommonName, c.NotAfter.Format(time.RFC3339))
+	}
+}
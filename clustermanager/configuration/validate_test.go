@@ -0,0 +1,211 @@
+package configuration
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/symcn/api"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func buildTestCertificate(t *testing.T, notBefore, notAfter time.Time) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func buildTestKubeconfig(t *testing.T, cluster *clientcmdapi.Cluster, authInfo *clientcmdapi.AuthInfo) string {
+	cfg := clientcmdapi.NewConfig()
+	cfg.Clusters["cluster"] = cluster
+	cfg.AuthInfos["user"] = authInfo
+	cfg.Contexts["ctx"] = &clientcmdapi.Context{Cluster: "cluster", AuthInfo: "user"}
+	cfg.CurrentContext = "ctx"
+	data, err := clientcmd.Write(*cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func diagnosticCodes(result ValidationResult) []string {
+	codes := []string{}
+	for _, d := range result.Diagnostics {
+		codes = append(codes, d.Code)
+	}
+	return codes
+}
+
+// testAuthProvider registered as symcn-test auth provider
+type testAuthProvider struct{}
+
+// testAuthProviderBuilt count symcn-test auth provider built
+var testAuthProviderBuilt int32
+
+func (testAuthProvider) WrapTransport(rt http.RoundTripper) http.RoundTripper { return rt }
+
+func (testAuthProvider) Login() error { return nil }
+
+func init() {
+	err := rest.RegisterAuthProviderPlugin("symcn-test", func(string, map[string]string, rest.AuthProviderConfigPersister) (rest.AuthProvider, error) {
+		atomic.AddInt32(&testAuthProviderBuilt, 1)
+		return testAuthProvider{}, nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func TestValidateClusterCfgInfo(t *testing.T) {
+	now := time.Now()
+	validCert, validKey := buildTestCertificate(t, now.Add(-time.Hour), now.Add(time.Hour*24*365))
+	expiredCert, expiredKey := buildTestCertificate(t, now.Add(-time.Hour*2), now.Add(-time.Hour))
+	expiringCert, _ := buildTestCertificate(t, now.Add(-time.Hour), now.Add(time.Hour))
+
+	cases := []struct {
+		name    string
+		info    api.ClusterCfgInfo
+		valid   bool
+		expects []string
+	}{
+		{
+			name: "valid",
+			info: BuildClusterCfgInfo("valid", api.KubeConfigTypeRawString, buildTestKubeconfig(t,
+				&clientcmdapi.Cluster{Server: "https://cluster", CertificateAuthorityData: validCert},
+				&clientcmdapi.AuthInfo{ClientCertificateData: validCert, ClientKeyData: validKey}), ""),
+			valid: true,
+		},
+		{
+			name:    "parse failed",
+			info:    BuildClusterCfgInfo("parse failed", api.KubeConfigTypeRawString, "invalid", ""),
+			expects: []string{CodeParseFailed},
+		},
+		{
+			name: "context not found",
+			info: BuildClusterCfgInfo("context not found", api.KubeConfigTypeRawString, buildTestKubeconfig(t,
+				&clientcmdapi.Cluster{Server: "https://cluster"}, &clientcmdapi.AuthInfo{Token: "token"}), "not-exist"),
+			expects: []string{CodeContextNotFound},
+		},
+		{
+			name: "expired",
+			info: BuildClusterCfgInfo("expired", api.KubeConfigTypeRawString, buildTestKubeconfig(t,
+				&clientcmdapi.Cluster{Server: "https://cluster", CertificateAuthorityData: expiringCert},
+				&clientcmdapi.AuthInfo{ClientCertificateData: expiredCert, ClientKeyData: expiredKey}), ""),
+			expects: []string{CodeCertificateExpiring, CodeCertificateExpired},
+		},
+		{
+			name: "rotating ca bundle",
+			info: BuildClusterCfgInfo("rotating ca bundle", api.KubeConfigTypeRawString, buildTestKubeconfig(t,
+				&clientcmdapi.Cluster{Server: "https://cluster", CertificateAuthorityData: append(append([]byte{}, expiredCert...), validCert...)},
+				&clientcmdapi.AuthInfo{Token: "token"}), ""),
+			valid:   true,
+			expects: []string{CodeCertificateExpired},
+		},
+		{
+			name: "expired ca bundle",
+			info: BuildClusterCfgInfo("expired ca bundle", api.KubeConfigTypeRawString, buildTestKubeconfig(t,
+				&clientcmdapi.Cluster{Server: "https://cluster", CertificateAuthorityData: expiredCert},
+				&clientcmdapi.AuthInfo{Token: "token"}), ""),
+			expects: []string{CodeCertificateExpired},
+		},
+		{
+			name: "registered auth provider",
+			info: BuildClusterCfgInfo("registered auth provider", api.KubeConfigTypeRawString, buildTestKubeconfig(t,
+				&clientcmdapi.Cluster{Server: "https://cluster"},
+				&clientcmdapi.AuthInfo{AuthProvider: &clientcmdapi.AuthProviderConfig{Name: "symcn-test"}}), ""),
+			valid: true,
+		},
+		{
+			name: "client certificate file not found",
+			info: BuildClusterCfgInfo("client certificate file not found", api.KubeConfigTypeRawString, buildTestKubeconfig(t,
+				&clientcmdapi.Cluster{Server: "https://cluster"},
+				&clientcmdapi.AuthInfo{ClientCertificate: "/symcn-not-exist/client.crt", ClientKeyData: validKey}), ""),
+			expects: []string{CodeInvalidClientCertificate},
+		},
+		{
+			name: "mismatch key",
+			info: BuildClusterCfgInfo("mismatch key", api.KubeConfigTypeRawString, buildTestKubeconfig(t,
+				&clientcmdapi.Cluster{Server: "https://cluster", CertificateAuthorityData: []byte("invalid")},
+				&clientcmdapi.AuthInfo{ClientCertificateData: validCert, ClientKeyData: expiredKey}), ""),
+			expects: []string{CodeInvalidCA, CodeInvalidClientCertificate},
+		},
+		{
+			name: "auth provider and exec",
+			info: BuildClusterCfgInfo("auth provider and exec", api.KubeConfigTypeRawString, buildTestKubeconfig(t,
+				&clientcmdapi.Cluster{Server: "https://cluster", InsecureSkipTLSVerify: true},
+				&clientcmdapi.AuthInfo{
+					AuthProvider: &clientcmdapi.AuthProviderConfig{Name: "gcp"},
+					Exec:         &clientcmdapi.ExecConfig{Command: "symcn-not-exist-command", APIVersion: "client.authentication.k8s.io/v1"},
+				}), ""),
+			expects: []string{CodeInsecureSkipTLSVerify, CodeUnsupportedAuthProvider, CodeExecCommandNotFound},
+		},
+		{
+			name:  "in cluster",
+			info:  BuildClusterCfgInfo("in cluster", api.KubeConfigTypeInCluster, "", ""),
+			valid: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result := ValidateClusterCfgInfo(c.info)
+			if result.Valid() != c.valid || (result.Err() == nil) != c.valid {
+				t.Errorf("expect valid %t, but got %+v", c.valid, result.Diagnostics)
+			}
+			codes := diagnosticCodes(result)
+			if len(codes) != len(c.expects) {
+				t.Fatalf("expect diagnostics %v, but got %v", c.expects, codes)
+			}
+			for i := range codes {
+				if codes[i] != c.expects[i] {
+					t.Errorf("expect diagnostics %v, but got %v", c.expects, codes)
+				}
+			}
+		})
+	}
+
+	if atomic.LoadInt32(&testAuthProviderBuilt) != 0 {
+		t.Error("auth provider should not be built when validate")
+	}
+	// probed not registered provider keeps unsupported
+	if codes := diagnosticCodes(ValidateClusterCfgInfo(cases[len(cases)-2].info)); len(codes) != 3 || codes[1] != CodeUnsupportedAuthProvider {
+		t.Errorf("expect auth provider unsupported again, but got %v", codes)
+	}
+
+	results, err := ValidateClusterCfgManager(&FakeConfiguration{
+		GetAllFunc: func() ([]api.ClusterCfgInfo, error) {
+			return []api.ClusterCfgInfo{cases[0].info, cases[1].info}, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || !results[0].Valid() || results[1].Valid() {
+		t.Errorf("expect validate all clusters of manager, but got %+v", results)
+	}
+}
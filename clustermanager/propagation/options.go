@@ -0,0 +1,126 @@
+package propagation
+
+import (
+	"errors"
+
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/configuration"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	defaultQueueName = "symcn-propagation"
+)
+
+// annotations on the source object
+// AnnotationClusters target cluster names, separated by comma
+// AnnotationClusterSelector target cluster label selector, such as env=prod,region in (a,b)
+// AnnotationOverrides per-cluster JSON patches, such as {"cluster-a":[{"op":"replace","path":"/data/key","value":"v"}]}
+// AnnotationStatus per-cluster sync status written by propagator
+// AnnotationSource set on the propagated objects, the namespace/name of source object
+const (
+	AnnotationPrefix          = "propagation.symcn.io/"
+	AnnotationClusters        = AnnotationPrefix + "clusters"
+	AnnotationClusterSelector = AnnotationPrefix + "cluster-selector"
+	AnnotationOverrides       = AnnotationPrefix + "overrides"
+	AnnotationStatus          = AnnotationPrefix + "status"
+	AnnotationSource          = AnnotationPrefix + "source"
+
+	// Finalizer prune propagated objects before the source object deleted
+	Finalizer = AnnotationPrefix + "finalizer"
+)
+
+// PrunePolicy decide what to do with the propagated objects when the cluster
+// is no longer selected or the source object is deleted
+type PrunePolicy string
+
+// PruneDelete delete propagated objects
+// PruneOrphan keep propagated objects in member clusters
+const (
+	PruneDelete PrunePolicy = "Delete"
+	PruneOrphan PrunePolicy = "Orphan"
+)
+
+// ClusterLabelsFunc returns labels of the cluster, used to match AnnotationClusterSelector
+type ClusterLabelsFunc func(cli api.MingleClient) map[string]string
+
+// Config propagation configuration
+type Config struct {
+	// SourceClient the manager cluster which source objects live
+	SourceClient api.MingleClient
+	// MultiClient the member clusters which source objects propagate to
+	MultiClient api.MultiMingleClient
+	// Object source object type, such as &corev1.ConfigMap{}
+	Object rtclient.Object
+	// Predicates filter source objects
+	Predicates []api.Predicate
+	// Scheme used to get GroupVersionKind of Object, default use client-go scheme
+	Scheme *runtime.Scheme
+	// ClusterLabelsFunc returns labels of the cluster, default use configuration.GetClusterLabels
+	ClusterLabelsFunc ClusterLabelsFunc
+	// PrunePolicy default PruneDelete
+	PrunePolicy PrunePolicy
+	// QueueName workqueue name, must unique with each propagator
+	QueueName   string
+	Threadiness int
+}
+
+type completedConfig struct {
+	*Config
+}
+
+// CompletedConfig propagation completed configuration
+type CompletedConfig struct {
+	*completedConfig
+}
+
+// NewConfig build propagation config
+func NewConfig(sourceClient api.MingleClient, multiClient api.MultiMingleClient, obj rtclient.Object) *Config {
+	return &Config{
+		SourceClient: sourceClient,
+		MultiClient:  multiClient,
+		Object:       obj,
+		PrunePolicy:  PruneDelete,
+		QueueName:    defaultQueueName,
+		Threadiness:  1,
+	}
+}
+
+// Complete check and set default value
+func Complete(cfg *Config) (*CompletedConfig, error) {
+	if cfg.SourceClient == nil {
+		return nil, errors.New("propagation source client is nil")
+	}
+	if cfg.MultiClient == nil {
+		return nil, errors.New("propagation multiclient is nil")
+	}
+	if cfg.Object == nil {
+		return nil, errors.New("propagation source object is nil")
+	}
+
+	if cfg.Scheme == nil {
+		cfg.Scheme = clientgoscheme.Scheme
+	}
+	if cfg.ClusterLabelsFunc == nil {
+		cfg.ClusterLabelsFunc = func(cli api.MingleClient) map[string]string {
+			return configuration.GetClusterLabels(cli.GetClusterCfgInfo())
+		}
+	}
+	if cfg.PrunePolicy == "" {
+		cfg.PrunePolicy = PruneDelete
+	}
+	if cfg.PrunePolicy != PruneDelete && cfg.PrunePolicy != PruneOrphan {
+		return nil, errors.New("propagation prune policy must be Delete or Orphan")
+	}
+	if cfg.QueueName == "" {
+		cfg.QueueName = defaultQueueName
+	}
+	return &CompletedConfig{&completedConfig{cfg}}, nil
+}
+
+// New build Propagator
+func (cc *CompletedConfig) New() *Propagator {
+	return &Propagator{CompletedConfig: cc, resync: make(chan struct{}, 1)}
+}
@@ -0,0 +1,377 @@
+package propagation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/handler"
+	"github.com/symcn/pkg/clustermanager/workqueue"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// Propagator watch source objects in the manager cluster and apply them to member clusters
+type Propagator struct {
+	*CompletedConfig
+	queue api.WorkQueue
+	// resync coalesce cluster added events, handled by resyncLoop
+	resync chan struct{}
+}
+
+// Start watch source objects and blocks until the context is cancelled
+func (p *Propagator) Start(ctx context.Context) error {
+	qc := workqueue.NewQueueConfig(p)
+	qc.Name = p.QueueName
+	qc.Threadiness = p.Threadiness
+	queue, err := workqueue.Completed(qc).NewQueue()
+	if err != nil {
+		return fmt.Errorf("build propagation queue failed %+v", err)
+	}
+	p.queue = queue
+
+	err = p.SourceClient.Watch(p.Object, queue, handler.NewDefaultTransformNamespacedNameEventHandler(), p.Predicates...)
+	if err != nil {
+		return fmt.Errorf("watch propagation source object failed %+v", err)
+	}
+	// new cluster connected, propagate all source objects
+	go p.resyncLoop(ctx)
+	p.MultiClient.AddClusterEventHandler(&clusterEventHandler{p: p})
+
+	return queue.Start(ctx)
+}
+
+// Reconcile implements api.Reconciler
+func (p *Propagator) Reconcile(ctx context.Context, req ktypes.NamespacedName) (api.NeedRequeue, time.Duration, error) {
+	source := p.Object.DeepCopyObject().(rtclient.Object)
+	err := p.SourceClient.GetCtrlRtClient().Get(ctx, req, source)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return api.Done, 0, nil
+		}
+		return api.Requeue, 0, err
+	}
+
+	statusMap := map[string]ClusterStatus{}
+	oldStatusList, err := GetStatus(source)
+	if err != nil {
+		klog.Warningf("source %s status invalid, reset: %+v", req, err)
+	}
+	for _, status := range oldStatusList {
+		statusMap[status.Cluster] = status
+	}
+
+	if !source.GetDeletionTimestamp().IsZero() {
+		return p.finalize(ctx, source, statusMap)
+	}
+
+	if p.PrunePolicy == PruneDelete && !controllerutil.ContainsFinalizer(source, Finalizer) {
+		controllerutil.AddFinalizer(source, Finalizer)
+		if err = p.SourceClient.GetCtrlRtClient().Update(ctx, source); err != nil {
+			return api.Requeue, 0, fmt.Errorf("add finalizer to source %s failed %+v", req, err)
+		}
+	}
+
+	desired, err := p.buildDesired(source)
+	if err != nil {
+		return api.Done, 0, err
+	}
+	overrides, err := getOverrides(source)
+	if err != nil {
+		// invalid overrides, wait source modified
+		klog.Errorf("source %s overrides invalid: %+v", req, err)
+		return api.Done, 0, nil
+	}
+
+	var failed bool
+	targets := p.selectClusters(source)
+	for name, cli := range targets {
+		status := ClusterStatus{Cluster: name, Synced: true}
+		if cli == nil {
+			status.Synced, status.Message = false, fmt.Sprintf("cluster %s not exist", name)
+		} else if err = p.apply(ctx, cli, desired, overrides[name]); err != nil {
+			status.Synced, status.Message = false, err.Error()
+		}
+		failed = failed || !status.Synced
+		statusMap[name] = status
+	}
+
+	// prune the clusters no longer selected
+	for name := range statusMap {
+		if _, ok := targets[name]; ok {
+			continue
+		}
+		if err = p.prune(ctx, name, desired); err != nil {
+			failed = true
+			statusMap[name] = ClusterStatus{Cluster: name, Message: fmt.Sprintf("prune failed %+v", err)}
+			continue
+		}
+		delete(statusMap, name)
+	}
+
+	if err = p.writeStatus(ctx, source, statusMap); err != nil {
+		return api.Requeue, 0, err
+	}
+	if failed {
+		return api.Requeue, 0, nil
+	}
+	return api.Done, 0, nil
+}
+
+// finalize prune all propagated objects and remove finalizer
+func (p *Propagator) finalize(ctx context.Context, source rtclient.Object, statusMap map[string]ClusterStatus) (api.NeedRequeue, time.Duration, error) {
+	if !controllerutil.ContainsFinalizer(source, Finalizer) {
+		return api.Done, 0, nil
+	}
+
+	desired, err := p.buildDesired(source)
+	if err != nil {
+		return api.Done, 0, err
+	}
+	for name := range statusMap {
+		if err = p.prune(ctx, name, desired); err != nil {
+			return api.Requeue, 0, fmt.Errorf("prune source %s/%s in cluster %s failed %+v", source.GetNamespace(), source.GetName(), name, err)
+		}
+	}
+
+	controllerutil.RemoveFinalizer(source, Finalizer)
+	if err = p.SourceClient.GetCtrlRtClient().Update(ctx, source); err != nil {
+		return api.Requeue, 0, fmt.Errorf("remove finalizer from source %s/%s failed %+v", source.GetNamespace(), source.GetName(), err)
+	}
+	return api.Done, 0, nil
+}
+
+// selectClusters returns target clusters by name and label selector,
+// the value is nil when the named cluster not exist.
+func (p *Propagator) selectClusters(source rtclient.Object) map[string]api.MingleClient {
+	annotations := source.GetAnnotations()
+	targets := map[string]api.MingleClient{}
+
+	names := sets.NewString()
+	for _, name := range strings.Split(annotations[AnnotationClusters], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names.Insert(name)
+		}
+	}
+
+	var selector labels.Selector
+	if value := strings.TrimSpace(annotations[AnnotationClusterSelector]); value != "" {
+		var err error
+		if selector, err = labels.Parse(value); err != nil {
+			klog.Errorf("source %s/%s cluster selector %s invalid: %+v", source.GetNamespace(), source.GetName(), value, err)
+		}
+	}
+
+	for _, cli := range p.MultiClient.GetAll() {
+		name := cli.GetClusterCfgInfo().GetName()
+		if names.Has(name) {
+			targets[name] = cli
+			continue
+		}
+		if selector != nil && !selector.Empty() && selector.Matches(labels.Set(p.clusterLabels(cli))) {
+			targets[name] = cli
+		}
+	}
+	for _, name := range names.List() {
+		if _, ok := targets[name]; !ok {
+			targets[name] = nil
+		}
+	}
+	return targets
+}
+
+func (p *Propagator) clusterLabels(cli api.MingleClient) map[string]string {
+	if p.ClusterLabelsFunc == nil {
+		return nil
+	}
+	return p.ClusterLabelsFunc(cli)
+}
+
+// buildDesired convert source object to the object propagated to member clusters
+func (p *Propagator) buildDesired(source rtclient.Object) (*unstructured.Unstructured, error) {
+	gvk, err := apiutil.GVKForObject(source, p.Scheme)
+	if err != nil {
+		return nil, err
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(source)
+	if err != nil {
+		return nil, fmt.Errorf("convert source %s/%s to unstructured failed %+v", source.GetNamespace(), source.GetName(), err)
+	}
+
+	// keep spec and data, drop status and server side metadata
+	delete(content, "status")
+	delete(content, "metadata")
+	desired := &unstructured.Unstructured{Object: content}
+	desired.SetGroupVersionKind(gvk)
+	desired.SetNamespace(source.GetNamespace())
+	desired.SetName(source.GetName())
+	desired.SetLabels(source.GetLabels())
+
+	annotations := map[string]string{}
+	for k, v := range source.GetAnnotations() {
+		if !strings.HasPrefix(k, AnnotationPrefix) {
+			annotations[k] = v
+		}
+	}
+	annotations[AnnotationSource] = ktypes.NamespacedName{Namespace: source.GetNamespace(), Name: source.GetName()}.String()
+	desired.SetAnnotations(annotations)
+	return desired, nil
+}
+
+// apply create or update the desired object with override in the cluster
+func (p *Propagator) apply(ctx context.Context, cli api.MingleClient, desired *unstructured.Unstructured, override jsonpatch.Patch) error {
+	obj := desired.DeepCopy()
+	if len(override) > 0 {
+		data, err := json.Marshal(obj.Object)
+		if err != nil {
+			return err
+		}
+		if data, err = override.Apply(data); err != nil {
+			return fmt.Errorf("apply override failed %+v", err)
+		}
+		obj = &unstructured.Unstructured{}
+		if err = json.Unmarshal(data, &obj.Object); err != nil {
+			return fmt.Errorf("unmarshal overridden object failed %+v", err)
+		}
+	}
+
+	c := cli.GetCtrlRtClient()
+	current := &unstructured.Unstructured{}
+	current.SetGroupVersionKind(obj.GroupVersionKind())
+	err := c.Get(ctx, rtclient.ObjectKeyFromObject(obj), current)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		return c.Create(ctx, obj)
+	}
+
+	if current.GetAnnotations()[AnnotationSource] != obj.GetAnnotations()[AnnotationSource] {
+		return fmt.Errorf("%s %s already exists and not propagated by %s", obj.GetKind(), rtclient.ObjectKeyFromObject(obj), obj.GetAnnotations()[AnnotationSource])
+	}
+	obj.SetResourceVersion(current.GetResourceVersion())
+	return c.Update(ctx, obj)
+}
+
+// prune delete the propagated object in the cluster with PruneDelete
+func (p *Propagator) prune(ctx context.Context, clusterName string, desired *unstructured.Unstructured) error {
+	if p.PrunePolicy != PruneDelete {
+		return nil
+	}
+	cli, err := p.MultiClient.GetWithName(clusterName)
+	if err != nil {
+		// cluster removed, nothing can do
+		klog.Warningf("prune %s in cluster %s skipped: %+v", rtclient.ObjectKeyFromObject(desired), clusterName, err)
+		return nil
+	}
+
+	c := cli.GetCtrlRtClient()
+	current := &unstructured.Unstructured{}
+	current.SetGroupVersionKind(desired.GroupVersionKind())
+	if err = c.Get(ctx, rtclient.ObjectKeyFromObject(desired), current); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if current.GetAnnotations()[AnnotationSource] != desired.GetAnnotations()[AnnotationSource] {
+		// not propagated by this source
+		return nil
+	}
+	if err = c.Delete(ctx, current); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// writeStatus write per-cluster status to the source object annotation
+func (p *Propagator) writeStatus(ctx context.Context, source rtclient.Object, statusMap map[string]ClusterStatus) error {
+	value, err := encodeStatus(statusMap)
+	if err != nil {
+		return err
+	}
+	if source.GetAnnotations()[AnnotationStatus] == value {
+		return nil
+	}
+
+	original := source.DeepCopyObject().(rtclient.Object)
+	annotations := source.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[AnnotationStatus] = value
+	source.SetAnnotations(annotations)
+	if err = p.SourceClient.GetCtrlRtClient().Patch(ctx, source, rtclient.MergeFrom(original)); err != nil {
+		return fmt.Errorf("write status to source %s/%s failed %+v", source.GetNamespace(), source.GetName(), err)
+	}
+	return nil
+}
+
+// enqueueAll add all source objects to queue
+func (p *Propagator) enqueueAll(ctx context.Context) {
+	if p.queue == nil {
+		return
+	}
+	gvk, err := apiutil.GVKForObject(p.Object, p.Scheme)
+	if err != nil {
+		klog.Errorf("get source GroupVersionKind failed: %+v", err)
+		return
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind + "List"})
+	if err = p.SourceClient.GetCtrlRtClient().List(ctx, list); err != nil {
+		klog.Errorf("list source %s failed: %+v", gvk.Kind, err)
+		return
+	}
+	_ = meta.EachListItem(list, func(obj runtime.Object) error {
+		o := obj.(rtclient.Object)
+		p.queue.Add(ktypes.NamespacedName{Namespace: o.GetNamespace(), Name: o.GetName()})
+		return nil
+	})
+}
+
+// resyncLoop enqueue all source objects when a cluster added,
+// OnAdd is called with the multiclient lock held, so list source objects here
+func (p *Propagator) resyncLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.resync:
+			p.enqueueAll(ctx)
+		}
+	}
+}
+
+// triggerResync never blocks, pending resync covers the new one
+func (p *Propagator) triggerResync() {
+	select {
+	case p.resync <- struct{}{}:
+	default:
+	}
+}
+
+type clusterEventHandler struct {
+	p *Propagator
+}
+
+func (h *clusterEventHandler) OnAdd(ctx context.Context, cli api.MingleClient) {
+	h.p.triggerResync()
+}
+
+func (h *clusterEventHandler) OnDelete(ctx context.Context, cli api.MingleClient) {}
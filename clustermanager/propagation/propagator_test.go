@@ -0,0 +1,224 @@
+package propagation
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/symcn/api"
+	"github.com/symcn/pkg/clustermanager/client"
+	"github.com/symcn/pkg/clustermanager/configuration"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type fakeMultiClient struct {
+	api.MultiMingleClient
+	clients map[string]api.MingleClient
+}
+
+func (f *fakeMultiClient) GetAll() []api.MingleClient {
+	list := make([]api.MingleClient, 0, len(f.clients))
+	for _, cli := range f.clients {
+		list = append(list, cli)
+	}
+	return list
+}
+
+func (f *fakeMultiClient) GetWithName(name string) (api.MingleClient, error) {
+	if cli, ok := f.clients[name]; ok {
+		return cli, nil
+	}
+	return nil, fmt.Errorf("cluster [%s] not exist", name)
+}
+
+func buildFakeClient(name string, objs ...rtclient.Object) *client.FakeClient {
+	runtimeClient := fake.NewClientBuilder().WithObjects(objs...).Build()
+	return &client.FakeClient{
+		ClusterCfg:          configuration.NewFakeClusterCfgInfo("", api.KubeConfigTypeRawString, "", name),
+		GetCtrlRtClientFunc: func() rtclient.Client { return runtimeClient },
+	}
+}
+
+func TestPropagator(t *testing.T) {
+	key := ktypes.NamespacedName{Namespace: "default", Name: "app-config"}
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: key.Namespace,
+			Name:      key.Name,
+			Labels:    map[string]string{"app": "foo"},
+			Annotations: map[string]string{
+				AnnotationClusters:        "cluster-1,cluster-4",
+				AnnotationClusterSelector: "env=prod",
+				AnnotationOverrides:       `{"cluster-2":[{"op":"replace","path":"/data/level","value":"debug"}]}`,
+			},
+		},
+		Data: map[string]string{"level": "info"},
+	}
+	sourceClient := buildFakeClient("manager", source)
+	conflict := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}
+	mc := &fakeMultiClient{clients: map[string]api.MingleClient{
+		"cluster-1": buildFakeClient("cluster-1"),
+		"cluster-2": buildFakeClient("cluster-2"),
+		"cluster-3": buildFakeClient("cluster-3", conflict),
+		"cluster-5": buildFakeClient("cluster-5"),
+	}}
+	clusterLabels := map[string]map[string]string{
+		"cluster-2": {"env": "prod"},
+		"cluster-3": {"env": "prod"},
+	}
+
+	cfg := NewConfig(sourceClient, mc, &corev1.ConfigMap{})
+	cfg.ClusterLabelsFunc = func(cli api.MingleClient) map[string]string {
+		return clusterLabels[cli.GetClusterCfgInfo().GetName()]
+	}
+	cc, err := Complete(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := cc.New()
+
+	getPropagated := func(cluster string) (*corev1.ConfigMap, error) {
+		cm := &corev1.ConfigMap{}
+		err := mc.clients[cluster].GetCtrlRtClient().Get(context.TODO(), key, cm)
+		return cm, err
+	}
+	getStatus := func() map[string]ClusterStatus {
+		cm := &corev1.ConfigMap{}
+		if err := sourceClient.GetCtrlRtClient().Get(context.TODO(), key, cm); err != nil {
+			t.Fatal(err)
+		}
+		statusList, err := GetStatus(cm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		statusMap := map[string]ClusterStatus{}
+		for _, status := range statusList {
+			statusMap[status.Cluster] = status
+		}
+		return statusMap
+	}
+
+	t.Run("propagate by name and label", func(t *testing.T) {
+		requeue, _, err := p.Reconcile(context.TODO(), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if requeue != api.Requeue {
+			t.Error("cluster-3 conflict and cluster-4 not exist, should requeue")
+		}
+
+		cm, err := getPropagated("cluster-1")
+		if err != nil || cm.Data["level"] != "info" || cm.Annotations[AnnotationSource] != key.String() {
+			t.Errorf("cluster-1 should be propagated, but got %+v %+v", cm, err)
+		}
+		if _, ok := cm.Annotations[AnnotationClusters]; ok {
+			t.Error("propagation annotations should not be propagated")
+		}
+		if cm, err = getPropagated("cluster-2"); err != nil || cm.Data["level"] != "debug" {
+			t.Errorf("cluster-2 should be propagated with override, but got %+v %+v", cm, err)
+		}
+		if cm, _ = getPropagated("cluster-3"); cm.Annotations[AnnotationSource] != "" {
+			t.Error("cluster-3 object not propagated by source should not be overwritten")
+		}
+		if _, err = getPropagated("cluster-5"); !apierrors.IsNotFound(err) {
+			t.Errorf("cluster-5 not selected, but got %+v", err)
+		}
+
+		statusMap := getStatus()
+		if len(statusMap) != 4 || !statusMap["cluster-1"].Synced || !statusMap["cluster-2"].Synced ||
+			statusMap["cluster-3"].Synced || statusMap["cluster-4"].Synced {
+			t.Errorf("unexpect status %+v", statusMap)
+		}
+	})
+
+	t.Run("prune not selected cluster", func(t *testing.T) {
+		clusterLabels["cluster-2"] = map[string]string{"env": "test"}
+		if _, _, err := p.Reconcile(context.TODO(), key); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := getPropagated("cluster-2"); !apierrors.IsNotFound(err) {
+			t.Errorf("cluster-2 should be pruned, but got %+v", err)
+		}
+		if _, ok := getStatus()["cluster-2"]; ok {
+			t.Error("cluster-2 status should be removed")
+		}
+	})
+
+	t.Run("prune all with finalizer", func(t *testing.T) {
+		cm := &corev1.ConfigMap{}
+		if err := sourceClient.GetCtrlRtClient().Get(context.TODO(), key, cm); err != nil {
+			t.Fatal(err)
+		}
+		if len(cm.Finalizers) != 1 || cm.Finalizers[0] != Finalizer {
+			t.Fatalf("source should have finalizer, but got %v", cm.Finalizers)
+		}
+
+		now := metav1.NewTime(time.Now())
+		cm.DeletionTimestamp = &now
+		if err := sourceClient.GetCtrlRtClient().Update(context.TODO(), cm); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := p.Reconcile(context.TODO(), key); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := getPropagated("cluster-1"); !apierrors.IsNotFound(err) {
+			t.Errorf("cluster-1 should be pruned, but got %+v", err)
+		}
+		if cm, _ := getPropagated("cluster-3"); cm.Name == "" {
+			t.Error("cluster-3 object not propagated by source should not be pruned")
+		}
+	})
+}
+
+type fakeQueue struct {
+	items chan interface{}
+}
+
+func (q *fakeQueue) Add(item interface{}) { q.items <- item }
+
+func (q *fakeQueue) Start(ctx context.Context) error { return nil }
+
+func TestClusterAddedResync(t *testing.T) {
+	source := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app-config"}}
+	sourceClient := buildFakeClient("manager", source)
+	cc, err := Complete(NewConfig(sourceClient, &fakeMultiClient{}, &corev1.ConfigMap{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := cc.New()
+	queue := &fakeQueue{items: make(chan interface{}, 10)}
+	p.queue = queue
+
+	// OnAdd must not list source objects synchronously
+	h := &clusterEventHandler{p: p}
+	h.OnAdd(context.TODO(), buildFakeClient("cluster-1"))
+	h.OnAdd(context.TODO(), buildFakeClient("cluster-2"))
+	if len(queue.items) != 0 {
+		t.Fatal("OnAdd should not enqueue synchronously")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.resyncLoop(ctx)
+
+	select {
+	case item := <-queue.items:
+		if item != (ktypes.NamespacedName{Namespace: "default", Name: "app-config"}) {
+			t.Errorf("unexpected item %v", item)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("source object not enqueued after cluster added")
+	}
+	// the two events coalesced into one resync
+	select {
+	case item := <-queue.items:
+		t.Errorf("unexpected resync item %v", item)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -0,0 +1,68 @@
+package propagation
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	rtclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ClusterStatus sync status of the source object in one cluster
+type ClusterStatus struct {
+	Cluster string `json:"cluster"`
+	Synced  bool   `json:"synced"`
+	Message string `json:"message,omitempty"`
+}
+
+// GetStatus returns per-cluster sync status of the source object
+func GetStatus(obj rtclient.Object) ([]ClusterStatus, error) {
+	value, ok := obj.GetAnnotations()[AnnotationStatus]
+	if !ok || value == "" {
+		return nil, nil
+	}
+
+	statusList := []ClusterStatus{}
+	if err := json.Unmarshal([]byte(value), &statusList); err != nil {
+		return nil, fmt.Errorf("unmarshal annotation %s failed %+v", AnnotationStatus, err)
+	}
+	return statusList, nil
+}
+
+func encodeStatus(statusMap map[string]ClusterStatus) (string, error) {
+	statusList := make([]ClusterStatus, 0, len(statusMap))
+	for _, status := range statusMap {
+		statusList = append(statusList, status)
+	}
+	sort.Slice(statusList, func(i, j int) bool { return statusList[i].Cluster < statusList[j].Cluster })
+
+	data, err := json.Marshal(statusList)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// getOverrides returns per-cluster JSON patches of the source object
+func getOverrides(obj rtclient.Object) (map[string]jsonpatch.Patch, error) {
+	value, ok := obj.GetAnnotations()[AnnotationOverrides]
+	if !ok || value == "" {
+		return nil, nil
+	}
+
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(value), &raw); err != nil {
+		return nil, fmt.Errorf("unmarshal annotation %s failed %+v", AnnotationOverrides, err)
+	}
+
+	overrides := make(map[string]jsonpatch.Patch, len(raw))
+	for cluster, data := range raw {
+		patch, err := jsonpatch.DecodePatch(data)
+		if err != nil {
+			return nil, fmt.Errorf("decode cluster %s override patch failed %+v", cluster, err)
+		}
+		overrides[cluster] = patch
+	}
+	return overrides, nil
+}
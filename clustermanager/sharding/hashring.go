@@ -0,0 +1,75 @@
+package sharding
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+)
+
+var (
+	defaultVirtualNodes = 100
+)
+
+// HashRing consistent hashing ring, each member has some virtual nodes,
+// when member join or leave, only the keys near it will move.
+type HashRing struct {
+	virtualNodes int
+	hashes       []uint32
+	nodes        map[uint32]string
+	members      []string
+}
+
+// NewHashRing build HashRing, virtualNodes less than 1 use default 100
+func NewHashRing(virtualNodes int, members ...string) *HashRing {
+	if virtualNodes < 1 {
+		virtualNodes = defaultVirtualNodes
+	}
+	hr := &HashRing{
+		virtualNodes: virtualNodes,
+		hashes:       make([]uint32, 0, len(members)*virtualNodes),
+		nodes:        make(map[uint32]string, len(members)*virtualNodes),
+		members:      make([]string, 0, len(members)),
+	}
+
+	uniq := make(map[string]struct{}, len(members))
+	for _, member := range members {
+		if _, ok := uniq[member]; ok || member == "" {
+			continue
+		}
+		uniq[member] = struct{}{}
+		hr.members = append(hr.members, member)
+
+		for i := 0; i < virtualNodes; i++ {
+			h := crc32.ChecksumIEEE([]byte(member + "#" + strconv.Itoa(i)))
+			if exist, ok := hr.nodes[h]; ok && exist < member {
+				// hash collision, keep stable with the smallest member
+				continue
+			}
+			if _, ok := hr.nodes[h]; !ok {
+				hr.hashes = append(hr.hashes, h)
+			}
+			hr.nodes[h] = member
+		}
+	}
+	sort.Slice(hr.hashes, func(i, j int) bool { return hr.hashes[i] < hr.hashes[j] })
+	sort.Strings(hr.members)
+	return hr
+}
+
+// Get returns the member which own the key, returns empty when ring is empty
+func (hr *HashRing) Get(key string) string {
+	if len(hr.hashes) == 0 {
+		return ""
+	}
+	h := crc32.ChecksumIEEE([]byte(key))
+	idx := sort.Search(len(hr.hashes), func(i int) bool { return hr.hashes[i] >= h })
+	if idx == len(hr.hashes) {
+		idx = 0
+	}
+	return hr.nodes[hr.hashes[idx]]
+}
+
+// Members returns all sorted members
+func (hr *HashRing) Members() []string {
+	return hr.members
+}
@@ -0,0 +1,49 @@
+package sharding
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestHashRing(t *testing.T) {
+	t.Run("empty ring", func(t *testing.T) {
+		hr := NewHashRing(0)
+		if hr.Get("cluster") != "" {
+			t.Error("empty ring should return empty owner")
+		}
+	})
+
+	t.Run("duplicate members", func(t *testing.T) {
+		hr := NewHashRing(10, "b", "a", "b", "")
+		if len(hr.Members()) != 2 || hr.Members()[0] != "a" || hr.Members()[1] != "b" {
+			t.Errorf("expect members [a b] but got %v", hr.Members())
+		}
+	})
+
+	t.Run("stable and minimal movement", func(t *testing.T) {
+		keys := make([]string, 0, 200)
+		for i := 0; i < 200; i++ {
+			keys = append(keys, fmt.Sprintf("cluster-%d", i))
+		}
+
+		before := NewHashRing(100, "r1", "r2", "r3")
+		after := NewHashRing(100, "r1", "r2", "r3", "r4")
+
+		count := map[string]int{}
+		for _, key := range keys {
+			if before.Get(key) != NewHashRing(100, "r3", "r1", "r2").Get(key) {
+				t.Fatalf("key %s owner not stable with members order", key)
+			}
+			owner := after.Get(key)
+			count[owner]++
+			if before.Get(key) != owner && owner != "r4" {
+				t.Errorf("key %s moved from %s to %s, should only move to new member", key, before.Get(key), owner)
+			}
+		}
+		for _, member := range after.Members() {
+			if count[member] == 0 {
+				t.Errorf("member %s not own any key", member)
+			}
+		}
+	})
+}
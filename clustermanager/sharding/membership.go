@@ -0,0 +1,381 @@
+package sharding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+var (
+	// GroupLabelKey label key of heartbeat Lease, value is sharding group
+	GroupLabelKey = "sharding.symcn.io/group"
+
+	// IdentityEnvKey the environment of stable identity, such as pod name from the downward API
+	IdentityEnvKey = "POD_NAME"
+
+	defaultNamespace          = "default"
+	defaultGroup              = "symcn-multi-client"
+	defaultLeaseDuration      = time.Second * 30
+	defaultRenewInterval      = time.Second * 10
+	defaultExpiredGracePeriod = time.Minute * 5
+	defaultExecTimeout        = time.Second * 5
+)
+
+// Config sharding configuration
+type Config struct {
+	// Enabled enable sharding member clusters across replicas
+	Enabled bool
+
+	// Namespace heartbeat Lease namespace in the manager cluster
+	Namespace string
+	// Group all replicas with same group share the member clusters
+	Group string
+	// Identity of this replica, default is the value of IdentityEnvKey, or hostname-random when empty
+	Identity string
+
+	// LeaseDuration a replica is considered dead when not renew with this duration
+	LeaseDuration time.Duration
+	// RenewInterval heartbeat interval, must less than LeaseDuration
+	RenewInterval time.Duration
+	// VirtualNodes virtual nodes of each replica in hash ring
+	VirtualNodes int
+	// ExpiredGracePeriod the Lease expired longer than it will be deleted, such as the crashed replica
+	ExpiredGracePeriod time.Duration
+}
+
+// NewConfig build Config with default value
+func NewConfig() *Config {
+	return &Config{
+		Enabled:            true,
+		Namespace:          defaultNamespace,
+		Group:              defaultGroup,
+		LeaseDuration:      defaultLeaseDuration,
+		RenewInterval:      defaultRenewInterval,
+		VirtualNodes:       defaultVirtualNodes,
+		ExpiredGracePeriod: defaultExpiredGracePeriod,
+	}
+}
+
+// Complete check and set default value
+func (c *Config) Complete() error {
+	if c.Namespace == "" {
+		c.Namespace = defaultNamespace
+	}
+	if c.Group == "" {
+		c.Group = defaultGroup
+	}
+	if c.Identity == "" {
+		c.Identity = os.Getenv(IdentityEnvKey)
+	}
+	if c.Identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("get hostname for sharding identity failed %+v", err)
+		}
+		c.Identity = hostname + "-" + rand.String(5)
+	}
+	if c.LeaseDuration <= 0 {
+		c.LeaseDuration = defaultLeaseDuration
+	}
+	if c.RenewInterval <= 0 {
+		c.RenewInterval = defaultRenewInterval
+	}
+	if c.RenewInterval >= c.LeaseDuration {
+		return fmt.Errorf("sharding renew interval %s must less than lease duration %s", c.RenewInterval, c.LeaseDuration)
+	}
+	if c.VirtualNodes < 1 {
+		c.VirtualNodes = defaultVirtualNodes
+	}
+	if c.ExpiredGracePeriod <= 0 {
+		c.ExpiredGracePeriod = defaultExpiredGracePeriod
+	}
+	return nil
+}
+
+// Membership register this replica with a heartbeat Lease,
+// and discover all alive replicas with same group.
+type Membership struct {
+	*Config
+
+	kubeInterface kubernetes.Interface
+	leaseName     string
+	onChange      func(members []string)
+
+	l    sync.RWMutex
+	ring *HashRing
+	// renewTime the last successful heartbeat, the others take over the clusters when expired
+	renewTime time.Time
+	expired   bool
+}
+
+// NewMembership build Membership, onChange is invoked when alive members changed,
+// or the heartbeat Lease of this replica expired and renewed again
+func NewMembership(kubeInterface kubernetes.Interface, cfg *Config, onChange func(members []string)) (*Membership, error) {
+	if kubeInterface == nil {
+		return nil, errors.New("sharding manager cluster kubernetes interface is nil")
+	}
+	if cfg == nil {
+		return nil, errors.New("sharding config is nil")
+	}
+	if err := cfg.Complete(); err != nil {
+		return nil, err
+	}
+
+	return &Membership{
+		Config:        cfg,
+		kubeInterface: kubeInterface,
+		leaseName:     leaseName(cfg.Group, cfg.Identity),
+		onChange:      onChange,
+		ring:          NewHashRing(cfg.VirtualNodes),
+	}, nil
+}
+
+// Sync renew heartbeat once and refresh alive members
+func (m *Membership) Sync(ctx context.Context) error {
+	defer m.checkRenewExpired()
+
+	renewTime := time.Now()
+	if err := m.heartbeat(ctx); err != nil {
+		return err
+	}
+	m.l.Lock()
+	m.renewTime = renewTime
+	m.l.Unlock()
+	return m.refresh(ctx)
+}
+
+// Start heartbeat and refresh members, blocks until the context is cancelled.
+// the heartbeat Lease will be deleted when exit, so the others take over quickly.
+func (m *Membership) Start(ctx context.Context) error {
+	timer := time.NewTicker(m.RenewInterval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			if err := m.Sync(ctx); err != nil {
+				klog.ErrorS(err, "sharding membership sync failed", "identity", m.Identity)
+			}
+		case <-ctx.Done():
+			m.leave()
+			return nil
+		}
+	}
+}
+
+// IsOwner returns true if the key belongs to this replica,
+// always false when heartbeat not renewed within LeaseDuration
+func (m *Membership) IsOwner(key string) bool {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	if m.renewExpired(time.Now()) {
+		return false
+	}
+	return m.ring.Get(key) == m.Identity
+}
+
+// Owner returns the replica identity which own the key
+func (m *Membership) Owner(key string) string {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	return m.ring.Get(key)
+}
+
+// Members returns all alive replicas
+func (m *Membership) Members() []string {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	return m.ring.Members()
+}
+
+// renewExpired must hold lock
+func (m *Membership) renewExpired(now time.Time) bool {
+	return now.After(m.renewTime.Add(m.LeaseDuration))
+}
+
+// checkRenewExpired invoke onChange when the Lease expired or renewed after expired,
+// so that the clusters taken over by others are stopped
+func (m *Membership) checkRenewExpired() {
+	m.l.Lock()
+	expired := m.renewExpired(time.Now())
+	changed := expired != m.expired
+	m.expired = expired
+	m.l.Unlock()
+
+	if !changed {
+		return
+	}
+	if expired {
+		klog.Warningf("sharding lease of %s not renewed within %s, give up all clusters", m.Identity, m.LeaseDuration)
+	} else {
+		klog.InfoS("Sharding lease renewed after expired.", "identity", m.Identity)
+	}
+	if m.onChange != nil {
+		m.onChange(m.Members())
+	}
+}
+
+func (m *Membership) heartbeat(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultExecTimeout)
+	defer cancel()
+
+	now := metav1.NewMicroTime(time.Now())
+	duration := int32(m.LeaseDuration / time.Second)
+	leaseClient := m.kubeInterface.CoordinationV1().Leases(m.Namespace)
+
+	lease, err := leaseClient.Get(ctx, m.leaseName, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("get sharding lease %s/%s failed %+v", m.Namespace, m.leaseName, err)
+		}
+		_, err = leaseClient.Create(ctx, &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      m.leaseName,
+				Namespace: m.Namespace,
+				Labels:    map[string]string{GroupLabelKey: m.Group},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &m.Identity,
+				LeaseDurationSeconds: &duration,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("create sharding lease %s/%s failed %+v", m.Namespace, m.leaseName, err)
+		}
+		return nil
+	}
+
+	lease.Spec.HolderIdentity = &m.Identity
+	lease.Spec.LeaseDurationSeconds = &duration
+	lease.Spec.RenewTime = &now
+	_, err = leaseClient.Update(ctx, lease, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("renew sharding lease %s/%s failed %+v", m.Namespace, m.leaseName, err)
+	}
+	return nil
+}
+
+func (m *Membership) refresh(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultExecTimeout)
+	defer cancel()
+
+	leaseList, err := m.kubeInterface.CoordinationV1().Leases(m.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: GroupLabelKey + "=" + m.Group,
+	})
+	if err != nil {
+		return fmt.Errorf("list sharding lease in %s failed %+v", m.Namespace, err)
+	}
+
+	now := time.Now()
+	members := aliveMembers(leaseList.Items, now)
+	m.deleteExpiredLeases(ctx, leaseList.Items, now)
+
+	m.l.Lock()
+	changed := !reflect.DeepEqual(m.ring.Members(), members)
+	if changed {
+		m.ring = NewHashRing(m.VirtualNodes, members...)
+	}
+	m.l.Unlock()
+
+	if changed {
+		klog.InfoS("Sharding members changed.", "identity", m.Identity, "members", members)
+		if m.onChange != nil {
+			m.onChange(members)
+		}
+	}
+	return nil
+}
+
+// deleteExpiredLeases delete the Leases expired longer than ExpiredGracePeriod,
+// the replicas exited without leave, such as crashed or OOM killed
+func (m *Membership) deleteExpiredLeases(ctx context.Context, leases []coordinationv1.Lease, now time.Time) {
+	for _, lease := range leases {
+		if lease.Name == m.leaseName {
+			continue
+		}
+		expire, ok := leaseExpireTime(lease)
+		if !ok || now.Before(expire.Add(m.ExpiredGracePeriod)) {
+			continue
+		}
+
+		// the replica may renew just now
+		rv := lease.ResourceVersion
+		err := m.kubeInterface.CoordinationV1().Leases(m.Namespace).Delete(ctx, lease.Name, metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{ResourceVersion: &rv},
+		})
+		if err != nil && !apierrors.IsNotFound(err) && !apierrors.IsConflict(err) {
+			klog.ErrorS(err, "delete expired sharding lease failed", "namespace", m.Namespace, "name", lease.Name)
+			continue
+		}
+		klog.InfoS("Delete expired sharding lease.", "namespace", m.Namespace, "name", lease.Name, "expire", expire)
+	}
+}
+
+func (m *Membership) leave() {
+	ctx, cancel := context.WithTimeout(context.TODO(), defaultExecTimeout)
+	defer cancel()
+
+	err := m.kubeInterface.CoordinationV1().Leases(m.Namespace).Delete(ctx, m.leaseName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.ErrorS(err, "delete sharding lease failed", "namespace", m.Namespace, "name", m.leaseName)
+	}
+}
+
+// aliveMembers returns sorted holder identity which lease not expired
+func aliveMembers(leases []coordinationv1.Lease, now time.Time) []string {
+	uniq := make(map[string]struct{}, len(leases))
+	members := make([]string, 0, len(leases))
+	for _, lease := range leases {
+		if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity == "" {
+			continue
+		}
+		expire, ok := leaseExpireTime(lease)
+		if !ok || now.After(expire) {
+			continue
+		}
+		if _, ok := uniq[*lease.Spec.HolderIdentity]; ok {
+			continue
+		}
+		uniq[*lease.Spec.HolderIdentity] = struct{}{}
+		members = append(members, *lease.Spec.HolderIdentity)
+	}
+	sort.Strings(members)
+	return members
+}
+
+// leaseExpireTime returns renew time add lease duration, false when not set
+func leaseExpireTime(lease coordinationv1.Lease) (time.Time, bool) {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return time.Time{}, false
+	}
+	return lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second), true
+}
+
+// leaseName build DNS-1123 lease name with group and identity
+func leaseName(group, identity string) string {
+	name := strings.ToLower(group + "-" + identity)
+	return strings.Trim(strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '.' {
+			return r
+		}
+		return '-'
+	}, name), "-.")
+}
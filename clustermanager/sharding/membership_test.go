@@ -0,0 +1,188 @@
+package sharding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestMembership(t *testing.T) {
+	kubeInterface := fake.NewSimpleClientset()
+
+	buildMembership := func(identity string) *Membership {
+		cfg := NewConfig()
+		cfg.Identity = identity
+		m, err := NewMembership(kubeInterface, cfg, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	m1 := buildMembership("Replica_1")
+	m2 := buildMembership("replica-2")
+
+	ctx := context.TODO()
+	if err := m1.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := m2.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// m1 discover m2
+	if err := m1.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m1.Members()) != 2 || len(m2.Members()) != 2 {
+		t.Fatalf("expect 2 members but got %v and %v", m1.Members(), m2.Members())
+	}
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("cluster-%d", i)
+		if m1.IsOwner(key) == m2.IsOwner(key) {
+			t.Errorf("cluster %s should belong to exactly one replica", key)
+		}
+	}
+
+	// m2 leave
+	m2.leave()
+	if err := m1.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("cluster-%d", i)
+		if !m1.IsOwner(key) {
+			t.Errorf("cluster %s should belong to the only replica", key)
+		}
+	}
+}
+
+func TestAliveMembers(t *testing.T) {
+	identity := func(s string) *string { return &s }
+	duration := int32(10)
+	now := time.Now()
+	renew := metav1.NewMicroTime(now.Add(-time.Second * 5))
+	expired := metav1.NewMicroTime(now.Add(-time.Second * 15))
+
+	leases := []coordinationv1.Lease{
+		{Spec: coordinationv1.LeaseSpec{HolderIdentity: identity("b"), LeaseDurationSeconds: &duration, RenewTime: &renew}},
+		{Spec: coordinationv1.LeaseSpec{HolderIdentity: identity("a"), LeaseDurationSeconds: &duration, RenewTime: &renew}},
+		{Spec: coordinationv1.LeaseSpec{HolderIdentity: identity("c"), LeaseDurationSeconds: &duration, RenewTime: &expired}},
+		{Spec: coordinationv1.LeaseSpec{HolderIdentity: identity("d")}},
+	}
+	members := aliveMembers(leases, now)
+	if len(members) != 2 || members[0] != "a" || members[1] != "b" {
+		t.Errorf("expect alive members [a b] but got %v", members)
+	}
+}
+
+func TestLeaseName(t *testing.T) {
+	if name := leaseName("group", "Host_Name-1"); name != "group-host-name-1" {
+		t.Errorf("expect group-host-name-1 but got %s", name)
+	}
+}
+
+func TestDeleteExpiredLeases(t *testing.T) {
+	identity := func(s string) *string { return &s }
+	duration := int32(10)
+	now := time.Now()
+	expired := metav1.NewMicroTime(now.Add(-time.Second * 15))
+	crashed := metav1.NewMicroTime(now.Add(-time.Hour))
+	buildLease := func(name string, renew *metav1.MicroTime) *coordinationv1.Lease {
+		return &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Namespace: defaultNamespace, Name: leaseName(defaultGroup, name), Labels: map[string]string{GroupLabelKey: defaultGroup}},
+			Spec:       coordinationv1.LeaseSpec{HolderIdentity: identity(name), LeaseDurationSeconds: &duration, RenewTime: renew},
+		}
+	}
+	kubeInterface := fake.NewSimpleClientset(buildLease("expired", &expired), buildLease("crashed", &crashed))
+
+	t.Setenv(IdentityEnvKey, "pod-1")
+	m, err := NewMembership(kubeInterface, NewConfig(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Identity != "pod-1" {
+		t.Errorf("expect identity from %s, but got %s", IdentityEnvKey, m.Identity)
+	}
+	if err = m.Sync(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+
+	leaseList, err := kubeInterface.CoordinationV1().Leases(defaultNamespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, lease := range leaseList.Items {
+		names[*lease.Spec.HolderIdentity] = true
+	}
+	if len(names) != 2 || !names["pod-1"] || !names["expired"] {
+		t.Errorf("expect lease crashed deleted after grace period, but got %v", names)
+	}
+}
+
+func TestRenewExpired(t *testing.T) {
+	kubeInterface := fake.NewSimpleClientset()
+	var unavailable int32
+	kubeInterface.PrependReactor("*", "leases", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if atomic.LoadInt32(&unavailable) == 1 {
+			return true, nil, errors.New("apiserver unavailable")
+		}
+		return false, nil, nil
+	})
+
+	var changed int32
+	cfg := NewConfig()
+	cfg.Identity = "replica-1"
+	m, err := NewMembership(kubeInterface, cfg, func(members []string) {
+		atomic.AddInt32(&changed, 1)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Sync(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if !m.IsOwner("cluster-1") {
+		t.Fatal("the only replica should own all clusters")
+	}
+	atomic.StoreInt32(&changed, 0)
+
+	// renew failed, still owner within lease duration
+	atomic.StoreInt32(&unavailable, 1)
+	if err = m.Sync(context.TODO()); err == nil {
+		t.Fatal("renew with apiserver unavailable should be error")
+	}
+	if !m.IsOwner("cluster-1") || atomic.LoadInt32(&changed) != 0 {
+		t.Errorf("expect owner within lease duration, but got owner %t changed %d", m.IsOwner("cluster-1"), changed)
+	}
+
+	// lease expired, give up all clusters
+	m.l.Lock()
+	m.renewTime = time.Now().Add(-m.LeaseDuration - time.Second)
+	m.l.Unlock()
+	if err = m.Sync(context.TODO()); err == nil {
+		t.Fatal("renew with apiserver unavailable should be error")
+	}
+	if m.IsOwner("cluster-1") || atomic.LoadInt32(&changed) != 1 {
+		t.Errorf("expect give up clusters after lease expired, but got owner %t changed %d", m.IsOwner("cluster-1"), changed)
+	}
+
+	// renewed, take over again
+	atomic.StoreInt32(&unavailable, 0)
+	if err = m.Sync(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if !m.IsOwner("cluster-1") || atomic.LoadInt32(&changed) != 2 {
+		t.Errorf("expect owner after renewed, but got owner %t changed %d", m.IsOwner("cluster-1"), changed)
+	}
+}